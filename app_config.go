@@ -3,7 +3,9 @@ package frame
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
@@ -12,6 +14,11 @@ import (
 // ConfigManager
 type ConfigManager struct {
 	*viper.Viper
+
+	// callbacks of config file changes, viper keeps only one
+	mu        sync.RWMutex
+	onChanges []func(fsnotify.Event)
+	watchOnce sync.Once
 }
 
 func setConfigFilePath(path string) {
@@ -42,6 +49,35 @@ func (cm *ConfigManager) ReadConfigObject(obj interface{}) error {
 	return nil
 }
 
+// OnChange add a callback of config file changes and start watching the file,
+// callbacks of the frame (eg: log levels reload) and the app are all called in order
+func (cm *ConfigManager) OnChange(fn func(in fsnotify.Event)) {
+	cm.mu.Lock()
+	cm.onChanges = append(cm.onChanges, fn)
+	cm.mu.Unlock()
+	cm.WatchConfig()
+}
+
+// OnConfigChange same as OnChange, viper.OnConfigChange replaces the callbacks of others
+func (cm *ConfigManager) OnConfigChange(fn func(in fsnotify.Event)) {
+	cm.OnChange(fn)
+}
+
+// WatchConfig watch the config file once, changes are sent to the callbacks of OnChange
+func (cm *ConfigManager) WatchConfig() {
+	cm.watchOnce.Do(func() {
+		cm.Viper.OnConfigChange(func(in fsnotify.Event) {
+			cm.mu.RLock()
+			fns := append([]func(in fsnotify.Event){}, cm.onChanges...)
+			cm.mu.RUnlock()
+			for _, fn := range fns {
+				fn(in)
+			}
+		})
+		cm.Viper.WatchConfig()
+	})
+}
+
 // LoadConfig read config
 func LoadConfig(configPath ...string) (*ConfigManager, *Config) {
 	var (
@@ -3,23 +3,32 @@ package frame
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config project config
 type Config struct {
-	Project      string       `json:"project"`
-	LogLevel     string       `json:"log_level" yaml:"log_level"  mapstructure:"log_level"`
-	LogMode      string       `json:"log_mode" yaml:"log_mode"  mapstructure:"log_mode"`
-	PrintConf    bool         `json:"print_conf" yaml:"print_conf"  mapstructure:"print_conf"`
-	EnableMetric bool         `json:"enable_metric" yaml:"enable_metric" mapstructure:"enable_metric"`
-	Env          string       `json:"env"`
-	HTTPServer   HTTPServer   `json:"http_server" yaml:"http_server" mapstructure:"http_server"`
-	HTTPClient   DoHTTPClient `json:"http_client" yaml:"http_client" mapstructure:"http_client"`
-	Mysql        MySQLConfig  `json:"mysql"`
-	Redis        RedisConfig  `json:"redis"`
+	Project      string           `json:"project"`
+	LogLevel     string           `json:"log_level" yaml:"log_level"  mapstructure:"log_level"`
+	LogMode      string           `json:"log_mode" yaml:"log_mode"  mapstructure:"log_mode"`
+	PrintConf    bool             `json:"print_conf" yaml:"print_conf"  mapstructure:"print_conf"`
+	EnableMetric bool             `json:"enable_metric" yaml:"enable_metric" mapstructure:"enable_metric"`
+	Env          string           `json:"env"`
+	HTTPServer   HTTPServer       `json:"http_server" yaml:"http_server" mapstructure:"http_server"`
+	GRPCServer   GRPCServerConfig `json:"grpc_server" yaml:"grpc_server" mapstructure:"grpc_server"`
+	HTTPClient   DoHTTPClient     `json:"http_client" yaml:"http_client" mapstructure:"http_client"`
+	Log          LogConfig        `json:"log"`
+	AccessLog    AccessLogConfig  `json:"access_log" yaml:"access_log" mapstructure:"access_log"`
+	Redact       RedactConfig     `json:"redact"`
+	TraceID      TraceIDConfig    `json:"trace_id" yaml:"trace_id" mapstructure:"trace_id"`
+	Tracing      TracingConfig    `json:"tracing"`
+	Metrics      MetricsConfig    `json:"metrics"`
+	Mysql        MySQLConfig      `json:"mysql"`
+	Redis        RedisConfig      `json:"redis"`
 }
 
 // DoHTTPClient http client config
@@ -28,12 +37,146 @@ type DoHTTPClient struct {
 	EnableMetric  bool `json:"enable_metric" yaml:"enable_metric" mapstructure:"enable_metric"`
 }
 
+// AccessLogConfig http and grpc access log config
+type AccessLogConfig struct {
+	Queue              LogQueueConfig   `json:"queue"`
+	MaxReqBodyBytes    int              `json:"max_req_body_bytes" yaml:"max_req_body_bytes" mapstructure:"max_req_body_bytes"`       // logged request body bytes, default 64KB
+	MaxRespBodyBytes   int              `json:"max_resp_body_bytes" yaml:"max_resp_body_bytes" mapstructure:"max_resp_body_bytes"`    // logged response body bytes, default 64KB
+	SuccessSampleRatio *float64         `json:"success_sample_ratio" yaml:"success_sample_ratio" mapstructure:"success_sample_ratio"` // ratio of success logs kept, errors are always logged, 0 drops all success logs, default 1
+	Routes             []AccessLogRoute `json:"routes"`
+}
+
+// AccessLogRoute per-route access log override, eg: upload and download routes opt out of body logging
+type AccessLogRoute struct {
+	Path            string `json:"path"`                                                                     // route template, eg: /files/:id
+	Method          string `json:"method"`                                                                   // default all methods
+	Disable         bool   `json:"disable"`                                                                  // no access log, metrics are still recorded
+	DisableReqBody  bool   `json:"disable_req_body" yaml:"disable_req_body" mapstructure:"disable_req_body"` // request body is not read
+	DisableRespBody bool   `json:"disable_resp_body" yaml:"disable_resp_body" mapstructure:"disable_resp_body"`
+}
+
+func (ac AccessLogConfig) getMaxReqBodyBytes() int {
+	if ac.MaxReqBodyBytes <= 0 {
+		return defaultAccessLogBodyBytes
+	}
+	return ac.MaxReqBodyBytes
+}
+
+func (ac AccessLogConfig) getMaxRespBodyBytes() int {
+	if ac.MaxRespBodyBytes <= 0 {
+		return defaultAccessLogBodyBytes
+	}
+	return ac.MaxRespBodyBytes
+}
+
+func (ac AccessLogConfig) getSuccessSampleRatio() float64 {
+	// nil when not configured
+	if ac.SuccessSampleRatio == nil {
+		return 1
+	}
+	return *ac.SuccessSampleRatio
+}
+
+// route override of the matched route template, zero value when not configured
+func (ac AccessLogConfig) route(method, fullPath string) AccessLogRoute {
+	for _, r := range ac.Routes {
+		if r.Path == fullPath && (r.Method == "" || strings.EqualFold(r.Method, method)) {
+			return r
+		}
+	}
+	return AccessLogRoute{}
+}
+
+// Validate check access log config
+func (ac AccessLogConfig) Validate() []error {
+	errs := ac.Queue.Validate()
+	if r := ac.getSuccessSampleRatio(); r < 0 || r > 1 {
+		errs = append(errs, errors.New("please fill in the correct access_log.success_sample_ratio in the configuration file, range 0 ~ 1"))
+	}
+	for _, r := range ac.Routes {
+		if !strings.HasPrefix(r.Path, "/") {
+			errs = append(errs, fmt.Errorf("access_log.routes path %q must be a route template starting with /, please reset it", r.Path))
+		}
+	}
+	return errs
+}
+
 // HTTPServer http config
 type HTTPServer struct {
-	Enable        bool               `json:"enable"`
-	EnableCors    bool               `json:"enable_cors" yaml:"enable_cors" mapstructure:"enable_cors"`
-	DisableReqLog bool               `json:"disable_req_log" yaml:"disable_req_log" mapstructure:"disable_req_log"` // default enable
-	Configs       []HTTPServerConfig `json:"configs"`
+	Enable             bool               `json:"enable"`
+	EnableCors         bool               `json:"enable_cors" yaml:"enable_cors" mapstructure:"enable_cors"`
+	DisableReqLog      bool               `json:"disable_req_log" yaml:"disable_req_log" mapstructure:"disable_req_log"`                // default enable
+	ShutdownTimeoutSec int                `json:"shutdown_timeout_sec" yaml:"shutdown_timeout_sec" mapstructure:"shutdown_timeout_sec"` // default 10s
+	RequestTimeoutSec  int                `json:"request_timeout_sec" yaml:"request_timeout_sec" mapstructure:"request_timeout_sec"`    // default no timeout
+	OpenAPI            OpenAPIConfig      `json:"openapi" yaml:"openapi" mapstructure:"openapi"`
+	Recovery           RecoveryConfig     `json:"recovery" yaml:"recovery" mapstructure:"recovery"`
+	ResponseMode       ResponseMode       `json:"response_mode" yaml:"response_mode" mapstructure:"response_mode"`          // envelope/problem, default envelope
+	ProblemTypeURI     string             `json:"problem_type_uri" yaml:"problem_type_uri" mapstructure:"problem_type_uri"` // problem type = problem_type_uri/code, default about:blank
+	Pagination         PaginationConfig   `json:"pagination" yaml:"pagination" mapstructure:"pagination"`
+	SSEHeartbeatSec    int                `json:"sse_heartbeat_sec" yaml:"sse_heartbeat_sec" mapstructure:"sse_heartbeat_sec"` // default 15s
+	WebSocket          WebSocketConfig    `json:"websocket" yaml:"websocket" mapstructure:"websocket"`
+	Configs            []HTTPServerConfig `json:"configs"`
+}
+
+// RecoveryConfig panic recovery config
+type RecoveryConfig struct {
+	Disable      bool   `json:"disable"`                                                         // default enable
+	DisableStack bool   `json:"disable_stack" yaml:"disable_stack" mapstructure:"disable_stack"` // default log stack
+	HTTPStatus   int    `json:"http_status" yaml:"http_status" mapstructure:"http_status"`       // default 500
+	Code         string `json:"code"`                                                            // default 500
+	Message      string `json:"message"`                                                         // default internal server error
+}
+
+func (rc RecoveryConfig) getHTTPStatus() int {
+	if rc.HTTPStatus <= 0 {
+		return http.StatusInternalServerError
+	}
+	return rc.HTTPStatus
+}
+
+func (rc RecoveryConfig) getCode() string {
+	if rc.Code == "" {
+		return ErrInternal.Code
+	}
+	return rc.Code
+}
+
+func (rc RecoveryConfig) getMessage() string {
+	if rc.Message == "" {
+		return ErrInternal.Reply
+	}
+	return rc.Message
+}
+
+// OpenAPIConfig openapi document config
+type OpenAPIConfig struct {
+	Enable  bool   `json:"enable"`
+	Server  string `json:"server"`                                        // serve on which http server, default metrics server
+	Path    string `json:"path"`                                          // default /openapi.json
+	UIPath  string `json:"ui_path" yaml:"ui_path" mapstructure:"ui_path"` // default /docs
+	Title   string `json:"title"`                                         // default project
+	Version string `json:"version"`                                       // default git commit
+}
+
+func (oc OpenAPIConfig) getServer() string {
+	if oc.Server == "" {
+		return defaultMetricsName
+	}
+	return oc.Server
+}
+
+func (oc OpenAPIConfig) getPath() string {
+	if oc.Path == "" {
+		return defaultOpenAPIPath
+	}
+	return oc.Path
+}
+
+func (oc OpenAPIConfig) getUIPath() string {
+	if oc.UIPath == "" {
+		return defaultOpenAPIUIPath
+	}
+	return oc.UIPath
 }
 
 // Validate check http server
@@ -50,7 +193,26 @@ func (hs HTTPServer) Validate() []error {
 			errs = append(errs, err...)
 		}
 	}
-	// port conflict
+	errs = append(errs, hs.WebSocket.Validate()...)
+	if !(hs.ResponseMode == "" || hs.ResponseMode == ResponseModeEnvelope || hs.ResponseMode == ResponseModeProblem) {
+		errs = append(errs, errors.New("please fill in the correct http_server.response_mode in the configuration file, choose one of: envelope/problem"))
+	}
+	// name and port conflict
+	names := map[string]bool{}
+	ports := map[string]string{}
+	for _, v := range hs.Configs {
+		if names[v.Name] {
+			errs = append(errs, fmt.Errorf("http server name %s is duplicated, please reset it", v.Name))
+		}
+		names[v.Name] = true
+		if n, ok := ports[v.Port]; ok {
+			errs = append(errs, fmt.Errorf("%s and %s http servers can't listen on the same port %s, please reset it", n, v.Name, v.Port))
+		}
+		ports[v.Port] = v.Name
+	}
+	if hs.OpenAPI.Enable && !isMetricName(hs.OpenAPI.getServer()) && !names[hs.OpenAPI.getServer()] {
+		errs = append(errs, fmt.Errorf("openapi server %s can't find in http_server.configs, please reset it", hs.OpenAPI.getServer()))
+	}
 	for _, v := range hs.Configs {
 		if isMetricPort(v.Port) && !(v.Name == defaultMetricName || v.Name == defaultMetricsName) {
 			errs = append(errs, fmt.Errorf("%s http port can't be set to %s, %s is the default metric port, please reset %s http port", v.Name, defaultMetricPort2, defaultMetricPort, v.Name))
@@ -117,6 +279,10 @@ func (mc MySQLConfig) Validate() []error {
 
 }
 
+func isMetricName(name string) bool {
+	return name == defaultMetricName || name == defaultMetricsName
+}
+
 func isMetricPort(port string) bool {
 	return port == defaultMetricPort || port == defaultMetricPort2
 }
@@ -223,13 +389,14 @@ func (hs HTTPServer) getMetricServerConfig() *HTTPServerConfig {
 	return nil
 }
 
-func (hs HTTPServer) getBusServerConfig() *HTTPServerConfig {
+func (hs HTTPServer) getBusServerConfigs() []HTTPServerConfig {
+	var list []HTTPServerConfig
 	for _, v := range hs.Configs {
 		if !(v.Name == defaultMetricName || v.Name == defaultMetricsName) {
-			return &v
+			list = append(list, v)
 		}
 	}
-	return nil
+	return list
 }
 
 func (c *Config) isEnableMySQLAutoMigrate(dbName string) bool {
@@ -268,6 +435,49 @@ func (c *Config) validate() []error {
 	if err := c.HTTPServer.Validate(); err != nil {
 		errs = append(errs, err...)
 	}
+	if err := c.TraceID.Validate(); err != nil {
+		errs = append(errs, err...)
+	}
+	if err := c.Log.Validate(); err != nil {
+		errs = append(errs, err...)
+	}
+	if admin := c.Log.Admin; admin.Enable && admin.Token == "" {
+		errs = append(errs, errors.New("please fill in the correct log.admin.token in the configuration file, it can't be empty when log.admin.enable is true"))
+	}
+	if admin := c.Log.Admin; admin.Enable && !isMetricName(admin.getServer()) {
+		found := false
+		for _, v := range c.HTTPServer.Configs {
+			found = found || v.Name == admin.getServer()
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("log admin server %s can't find in http_server.configs, please reset it", admin.getServer()))
+		}
+	}
+	if err := c.Redact.Validate(); err != nil {
+		errs = append(errs, err...)
+	}
+	if err := c.AccessLog.Validate(); err != nil {
+		errs = append(errs, err...)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		errs = append(errs, err...)
+	}
+	if err := c.Metrics.Validate(); err != nil {
+		errs = append(errs, err...)
+	}
+	if err := c.GRPCServer.Validate(); err != nil {
+		errs = append(errs, err...)
+	}
+	if c.GRPCServer.Enable {
+		for _, v := range c.HTTPServer.Configs {
+			if c.HTTPServer.Enable && v.Port == c.GRPCServer.Port {
+				errs = append(errs, fmt.Errorf("grpc server and %s http server can't listen on the same port %s, please reset it", v.Name, v.Port))
+			}
+		}
+		if c.EnableMetric && c.GRPCServer.Port == c.getMetricPort() {
+			errs = append(errs, fmt.Errorf("grpc server can't listen on the metric port %s, please reset it", c.GRPCServer.Port))
+		}
+	}
 	if err := c.Mysql.Validate(); err != nil {
 		errs = append(errs, err...)
 	}
@@ -281,12 +491,23 @@ func (c *Config) validate() []error {
 
 }
 
+func (c *Config) getRequestTimeout() time.Duration {
+	return time.Duration(c.HTTPServer.RequestTimeoutSec) * time.Second
+}
+
 func (c *Config) getMetricPort() string {
-	return c.HTTPServer.getMetricServerConfig().Port
+	mc := c.HTTPServer.getMetricServerConfig()
+	if mc == nil {
+		return defaultMetricPort
+	}
+	return mc.Port
 }
 
-func (c *Config) getServerPort() string {
-	return c.HTTPServer.getBusServerConfig().Port
+func (c *Config) getShutdownTimeout() time.Duration {
+	if c.HTTPServer.ShutdownTimeoutSec <= 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(c.HTTPServer.ShutdownTimeoutSec) * time.Second
 }
 
 func getConfigFromEnv() (t, path string) {
@@ -2,6 +2,7 @@ package frame
 
 import (
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm/logger"
@@ -26,6 +27,7 @@ var (
 )
 
 var (
+	defaultServerName  = "server"
 	defaultMetricName  = "metric"
 	defaultMetricsName = "metrics"
 	defaultMetricPath  = "/metrics"
@@ -33,6 +35,82 @@ var (
 	defaultMetricPort2 = "9090"
 )
 
+var defaultShutdownTimeout = 10 * time.Second
+
+var defaultTraceIDMaxLength = 128
+
+var (
+	defaultRedactMask    = "***"
+	defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+)
+
+// defaultMetricBuckets histogram buckets in seconds
+var defaultMetricBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20}
+
+// metric label values
+const (
+	unmatchedRouteLabel         = "unmatched" // requests not matching any route, eg: 404
+	collapsedLabel              = "other"     // values after metrics.max_label_values
+	defaultMetricMaxLabelValues = 1000
+)
+
+var (
+	defaultLogAdminPath     = "/admin/log/level"
+	defaultTraceLogLevelTTL = 10 * time.Minute
+	maxTraceLogLevelTTL     = time.Hour
+	maxTraceLogLevels       = 100 // trace ids with a log level at the same time
+)
+
+// defaultLogFileMaxSizeMB size of log file rotation
+var defaultLogFileMaxSizeMB = 100
+
+// defaultAccessLogBodyBytes max logged request/response body bytes
+var defaultAccessLogBodyBytes = 64 << 10
+
+// truncatedMarker appended to truncated log bodies
+var truncatedMarker = "...[truncated]"
+
+var (
+	defaultLogQueueSize        = 4096
+	defaultLogQueueWorkers     = 2
+	defaultLogQueueSampleRatio = 0.1
+)
+
+var defaultSSEHeartbeat = 15 * time.Second
+
+var (
+	defaultWSPingInterval         = 30 * time.Second
+	defaultWSPongWait             = 60 * time.Second
+	defaultWSWriteTimeout         = 10 * time.Second
+	defaultWSMaxMessageSize int64 = 1 << 20
+)
+
+// grpcMethod method label of grpc metrics
+var grpcMethod = "GRPC"
+
+// websocket message direction
+var (
+	wsDirectionIn  = "in"
+	wsDirectionOut = "out"
+)
+
+var (
+	defaultOpenAPIPath    = "/openapi.json"
+	defaultOpenAPIUIPath  = "/docs"
+	defaultOpenAPIVersion = "0.0.0"
+)
+
+var (
+	defaultPageSize    = 20
+	defaultMaxPageSize = 100
+)
+
+// defaultMultipartMemory max memory of multipart form parsing
+var defaultMultipartMemory int64 = 32 << 20
+
+// frameContextKey gin context key of the frame context
+var frameContextKey = "_frame/context"
+
 var logm = map[string]logrus.Level{
 	logLevelPanic: logrus.PanicLevel,
 	logLevelFatal: logrus.FatalLevel,
@@ -76,6 +154,8 @@ type TraceLogType string
 var (
 	TraceLogRouter     TraceLogType = "router"
 	TraceLogHTTPClient TraceLogType = "http_client"
+	TraceLogWebSocket  TraceLogType = "websocket"
+	TraceLogGRPC       TraceLogType = "grpc"
 )
 
 var (
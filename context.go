@@ -2,6 +2,9 @@ package frame
 
 import (
 	"context"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -11,31 +14,144 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-// Context frame context
+// Context frame context, one per request, shared by all middleware and handlers.
+// Context is recycled when the request is finished, use Copy when it is used in a goroutine.
 type Context struct {
 	Gtx           *gin.Context
 	config        *Config
 	configManager *ConfigManager
 	dbClients     *DBMultiClient
 	redisClients  *RedisMultiClient
+	metrics       *metrics
+	logQueue      *logQueue // access log queue of the app, nil writes inline
 	*logrus.Entry
-	httpClient *req.Client
-	traceID    string
+	httpClient   *req.Client
+	traceID      string
+	responseMode ResponseMode
+	// ctx request context of non-http calls, eg: grpc
+	ctx context.Context
+
+	// reply written by response helpers, used by access log and metrics
+	replied   bool
+	replyCode string
+	replyMsg  string
+	// streaming response is written by Stream/SSE
+	streaming bool
+	// skipAccessLog set by SkipAccessLog
+	skipAccessLog bool
+	// deadlines set by TimeoutFunc, lifted when the response is streamed
+	deadlines []*requestDeadline
+	// describe set on the probe context of route registration, handlers of Handle fill it instead of serving
+	describe *handlerMeta
+
+	// keys key/value store of this request
+	mu   sync.RWMutex
+	keys map[string]interface{}
+}
+
+// reset clear request data before the context is put back to the pool
+func (c *Context) reset() {
+	c.Gtx = nil
+	c.Entry = nil
+	c.httpClient = nil
+	c.traceID = ""
+	c.responseMode = ""
+	c.ctx = nil
+	c.replied = false
+	c.replyCode = ""
+	c.replyMsg = ""
+	c.streaming = false
+	c.skipAccessLog = false
+	c.deadlines = c.deadlines[:0]
+	c.mu.Lock()
+	for k := range c.keys {
+		delete(c.keys, k)
+	}
+	c.mu.Unlock()
+}
+
+// Copy return a copy of the context that can be safely used outside the request,
+// eg: in a goroutine
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		config:        c.config,
+		configManager: c.configManager,
+		dbClients:     c.dbClients,
+		redisClients:  c.redisClients,
+		metrics:       c.metrics,
+		logQueue:      c.logQueue,
+		Entry:         c.Entry,
+		httpClient:    c.httpClient,
+		traceID:       c.GetTraceID(),
+		responseMode:  c.responseMode,
+		ctx:           c.ctx,
+	}
+	if c.Gtx != nil {
+		cp.Gtx = c.Gtx.Copy()
+	}
+	c.mu.RLock()
+	for k, v := range c.keys {
+		cp.Set(k, v)
+	}
+	c.mu.RUnlock()
+	return cp
+}
+
+// Set store a new key/value pair for this request, eg: the authenticated user
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[string]interface{})
+	}
+	c.keys[key] = value
+}
+
+// Get return the value for the given key, ok is false when key not exists
+func (c *Context) Get(key string) (value interface{}, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok = c.keys[key]
+	return
+}
+
+// MustGet return the value for the given key, panic when key not exists
+func (c *Context) MustGet(key string) interface{} {
+	if value, ok := c.Get(key); ok {
+		return value
+	}
+	panic("key \"" + key + "\" does not exist")
 }
 
 // GetTraceID return trace id from context
 func (c *Context) GetTraceID() string {
+	if c.traceID != "" {
+		return c.traceID
+	}
 	if c.Gtx == nil {
-		if c.traceID != "" {
-			return c.traceID
-		}
 		return ""
 	}
-	return c.Gtx.GetHeader(TraceIDKey)
+	return c.Gtx.GetHeader(getTraceIDConfig().getHeader())
+}
+
+// setTraceID set request trace id, log entry carry the new trace id
+func (c *Context) setTraceID(traceID string) {
+	c.traceID = traceID
+	if c.Entry != nil {
+		// entries of the trace are written at its level of SetTraceLogLevel
+		entry := c.Entry.WithField(TraceIDKey, traceID)
+		entry.Logger = traceLogger(LoggerApp, traceID)
+		c.Entry = entry
+	}
 }
 
-// DoHTTP return http client
+// DoHTTP return http client, the client is created on first use and carry the trace id,
+// requests are bound to the request context at the time they are sent
 func (c *Context) DoHTTP() *req.Client {
+	if c.httpClient == nil {
+		c.httpClient = getHTTPClient(c.config, c.metrics, c.GetSetTraceHeader()).
+			OnBeforeRequest(bindRequestContext(c.WithTraceContext))
+	}
 	return c.httpClient
 }
 
@@ -43,11 +159,52 @@ func (c *Context) getGormLogger() logger.Interface {
 	return newGormLogger(c.config).LogMode(log2gormLevel(c.config.LogLevel))
 }
 
-// WithTraceContext return context
+// WithTraceContext return a context derived from the request context carrying the trace id,
+// it is cancelled when the client is gone or the request deadline is exceeded.
+// Unlike the Context itself, the returned context is still valid after the request is recycled.
 func (c *Context) WithTraceContext() context.Context {
 	id := c.GetTraceID()
-	pc := context.Background()
-	return context.WithValue(pc, TraceIDKey, id)
+	return context.WithValue(c.stdContext(), TraceIDKey, id)
+}
+
+// stdContext return the request context, context.Background when there is no request
+func (c *Context) stdContext() context.Context {
+	if c.Gtx != nil && c.Gtx.Request != nil {
+		return c.Gtx.Request.Context()
+	}
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// Deadline implement context.Context, return the request deadline
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.stdContext().Deadline()
+}
+
+// Done implement context.Context, closed when the client is gone or the request deadline is exceeded
+func (c *Context) Done() <-chan struct{} {
+	return c.stdContext().Done()
+}
+
+// Err implement context.Context
+func (c *Context) Err() error {
+	return c.stdContext().Err()
+}
+
+// Value implement context.Context, TraceIDKey return the trace id,
+// string keys are looked up in the key/value store first
+func (c *Context) Value(key interface{}) interface{} {
+	if key == TraceIDKey {
+		return c.GetTraceID()
+	}
+	if k, ok := key.(string); ok {
+		if v, ok := c.Get(k); ok {
+			return v
+		}
+	}
+	return c.stdContext().Value(key)
 }
 
 // GetDB get db client
@@ -98,11 +255,23 @@ func (c *Context) GetSetTraceHeader() string {
 		return traceID
 	}
 	traceID = generateTraceID(c.config.Project)
-	c.Gtx.Header(TraceIDKey, traceID)
-	c.traceID = traceID
+	if c.Gtx != nil {
+		c.Gtx.Header(getTraceIDConfig().getOutgoingHeader(), traceID)
+	}
+	c.setTraceID(traceID)
 	return traceID
 }
 
+// SkipAccessLog do not write the access log of this request, metrics are still recorded
+func (c *Context) SkipAccessLog() {
+	c.skipAccessLog = true
+}
+
+// Slog slog logger of the request carrying trace_id, records are written by the app logger
+func (c *Context) Slog() *slog.Logger {
+	return slog.New(newSlogHandler(c.GetLogger()))
+}
+
 // GetLogger get ctx log
 func (c *Context) GetLogger() *logrus.Entry {
 	traceID := c.GetSetTraceHeader()
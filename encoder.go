@@ -0,0 +1,236 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Encoder response body encoder
+type Encoder interface {
+	// ContentType response Content-Type
+	ContentType() string
+	// Encode write v to w
+	Encode(w io.Writer, v interface{}) error
+}
+
+// mime types of built-in encoders
+const (
+	MIMEJSON     = "application/json"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEMsgPack  = "application/x-msgpack"
+	MIMEMsgPack2 = "application/msgpack"
+	MIMEProtobuf = "application/x-protobuf"
+)
+
+type encoderRegistry struct {
+	sync.RWMutex
+	m map[string]Encoder
+}
+
+var encoders = &encoderRegistry{m: map[string]Encoder{
+	MIMEJSON:     jsonEncoder{},
+	MIMEXML:      xmlEncoder{contentType: MIMEXML},
+	MIMEXML2:     xmlEncoder{contentType: MIMEXML2},
+	MIMEMsgPack:  msgpackEncoder{contentType: MIMEMsgPack},
+	MIMEMsgPack2: msgpackEncoder{contentType: MIMEMsgPack2},
+	MIMEProtobuf: protobufEncoder{},
+}}
+
+// RegisterEncoder register encoder for the Accept mime type, replace the old one
+// eg: frame.RegisterEncoder("application/cbor", cborEncoder{})
+func RegisterEncoder(mime string, enc Encoder) {
+	encoders.Lock()
+	defer encoders.Unlock()
+	encoders.m[strings.ToLower(mime)] = enc
+}
+
+func getEncoder(mime string) (Encoder, bool) {
+	encoders.RLock()
+	defer encoders.RUnlock()
+	enc, ok := encoders.m[mime]
+	return enc, ok
+}
+
+// negotiateEncoder pick encoder by Accept header q order, default json
+func (c *Context) negotiateEncoder() Encoder {
+	if c.Gtx == nil || c.Gtx.Request == nil {
+		return jsonEncoder{}
+	}
+	for _, mime := range parseAccept(c.Gtx.GetHeader("Accept")) {
+		if enc, ok := getEncoder(mime); ok {
+			return enc
+		}
+		if mime == "*/*" || mime == "application/*" {
+			break
+		}
+	}
+	return jsonEncoder{}
+}
+
+// parseAccept return mime types ordered by q, eg: application/xml;q=0.9, application/json => [application/json application/xml]
+func parseAccept(accept string) []string {
+	list := parseAcceptQ(accept)
+	mimes := make([]string, 0, len(list))
+	for _, m := range list {
+		if m.q > 0 {
+			mimes = append(mimes, m.mime)
+		}
+	}
+	return mimes
+}
+
+// mimeQ mime type of Accept and its q
+type mimeQ struct {
+	mime string
+	q    float64
+}
+
+// parseAcceptQ return mime types ordered by q, refused ones with q=0 are kept at the end
+func parseAcceptQ(accept string) []mimeQ {
+	var list []mimeQ
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		mime := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v := strings.TrimSpace(f); strings.HasPrefix(v, "q=") {
+				if n, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = n
+				}
+			}
+		}
+		list = append(list, mimeQ{mime: mime, q: q})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+	return list
+}
+
+// render write resp with the negotiated encoder, the code and message are kept for access log and metrics
+func (c *Context) render(httpCode int, resp *Response) {
+	c.replied = true
+	c.replyCode = resp.Code
+	c.replyMsg = resp.Message
+	enc := c.negotiateEncoder()
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, resp); err != nil {
+		c.Errorf("encode response with %s failed, %v", enc.ContentType(), err)
+		if _, ok := enc.(jsonEncoder); ok {
+			c.Gtx.AbortWithStatus(500)
+			return
+		}
+		// fallback json
+		buf.Reset()
+		enc = jsonEncoder{}
+		if err := enc.Encode(&buf, resp); err != nil {
+			c.Gtx.AbortWithStatus(500)
+			return
+		}
+	}
+	c.Gtx.Data(httpCode, enc.ContentType(), buf.Bytes())
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return MIMEJSON + "; charset=utf-8"
+}
+
+func (jsonEncoder) Encode(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+type xmlEncoder struct {
+	contentType string
+}
+
+func (e xmlEncoder) ContentType() string {
+	return e.contentType + "; charset=utf-8"
+}
+
+func (xmlEncoder) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+type msgpackEncoder struct {
+	contentType string
+}
+
+func (e msgpackEncoder) ContentType() string {
+	return e.contentType
+}
+
+// msgpackHandle struct fields use codec or json tag
+var msgpackHandle = new(codec.MsgpackHandle)
+
+func (msgpackEncoder) Encode(w io.Writer, v interface{}) error {
+	return codec.NewEncoder(w, msgpackHandle).Encode(v)
+}
+
+// protobufEncoder proto.Message is encoded directly,
+// other values (eg: *Response) are encoded as google.protobuf.Struct
+type protobufEncoder struct{}
+
+func (protobufEncoder) ContentType() string {
+	return MIMEProtobuf
+}
+
+func (protobufEncoder) Encode(w io.Writer, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		s, err := toProtoStruct(v)
+		if err != nil {
+			return err
+		}
+		msg = s
+	}
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// toProtoStruct convert v to google.protobuf.Struct through json, proto.Message data use protojson
+func toProtoStruct(v interface{}) (*structpb.Struct, error) {
+	if resp, ok := v.(*Response); ok {
+		if msg, ok := resp.Data.(proto.Message); ok {
+			data, err := protojson.Marshal(msg)
+			if err != nil {
+				return nil, err
+			}
+			cp := *resp
+			cp.Data = json.RawMessage(data)
+			v = &cp
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(m)
+}
@@ -0,0 +1,184 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newAcceptContext context of a request with the Accept header
+func newAcceptContext(accept string) (*Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	gtx, _ := gin.CreateTestContext(w)
+	gtx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		gtx.Request.Header.Set("Accept", accept)
+	}
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return &Context{Gtx: gtx, Entry: logrus.NewEntry(l)}, w
+}
+
+// registerTestEncoder register an encoder for the test and remove it afterwards
+func registerTestEncoder(t *testing.T, mime string, enc Encoder) {
+	RegisterEncoder(mime, enc)
+	t.Cleanup(func() {
+		encoders.Lock()
+		delete(encoders.m, mime)
+		encoders.Unlock()
+	})
+}
+
+// failEncoder encoder always failing
+type failEncoder struct{}
+
+func (failEncoder) ContentType() string {
+	return "application/x-fail"
+}
+
+func (failEncoder) Encode(w io.Writer, v interface{}) error {
+	return errors.New("fail")
+}
+
+func TestParseAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   []string
+	}{
+		{name: "empty", accept: "", want: []string{}},
+		{name: "single", accept: "application/json", want: []string{"application/json"}},
+		{name: "sorted by q", accept: "application/xml;q=0.9, application/json", want: []string{"application/json", "application/xml"}},
+		{name: "stable for equal q", accept: "text/xml, application/xml", want: []string{"text/xml", "application/xml"}},
+		{name: "q zero dropped", accept: "application/xml;q=0, application/json;q=0.5", want: []string{"application/json"}},
+		{name: "invalid q is 1", accept: "application/xml;q=x, application/json;q=0.5", want: []string{"application/xml", "application/json"}},
+		{name: "lower case and params", accept: "Application/MsgPack; charset=utf-8", want: []string{"application/msgpack"}},
+		{name: "empty items", accept: ", ,application/json", want: []string{"application/json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAccept(tt.accept); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAccept() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoder(t *testing.T) {
+	registerTestEncoder(t, "application/x-test", failEncoder{})
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{name: "no accept", accept: "", want: "application/json; charset=utf-8"},
+		{name: "json", accept: "application/json", want: "application/json; charset=utf-8"},
+		{name: "xml", accept: "application/xml", want: "application/xml; charset=utf-8"},
+		{name: "text xml", accept: "text/xml", want: "text/xml; charset=utf-8"},
+		{name: "msgpack", accept: "application/x-msgpack", want: MIMEMsgPack},
+		{name: "msgpack alias", accept: "application/msgpack", want: MIMEMsgPack2},
+		{name: "protobuf", accept: "application/x-protobuf", want: MIMEProtobuf},
+		{name: "q order", accept: "application/xml;q=0.5, application/x-protobuf;q=0.8", want: MIMEProtobuf},
+		{name: "unknown skipped", accept: "text/html, application/xml;q=0.9", want: "application/xml; charset=utf-8"},
+		{name: "wildcard stops", accept: "text/html, */*;q=0.8, application/xml;q=0.5", want: "application/json; charset=utf-8"},
+		{name: "registered encoder", accept: "application/x-test", want: "application/x-fail"},
+		{name: "unknown only", accept: "text/html", want: "application/json; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newAcceptContext(tt.accept)
+			if got := c.negotiateEncoder().ContentType(); got != tt.want {
+				t.Errorf("negotiateEncoder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := (&Context{}).negotiateEncoder().ContentType(); got != "application/json; charset=utf-8" {
+		t.Errorf("negotiateEncoder() without gin context = %v, want json", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	registerTestEncoder(t, "application/x-test", failEncoder{})
+	resp := &Response{Code: "0", Message: "ok", Data: "hello"}
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+	}{
+		{name: "json", accept: "application/json", wantContentType: "application/json; charset=utf-8"},
+		{name: "xml", accept: "application/xml", wantContentType: "application/xml; charset=utf-8"},
+		{name: "failed encoder falls back to json", accept: "application/x-test", wantContentType: "application/json; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newAcceptContext(tt.accept)
+			c.render(http.StatusCreated, resp)
+			if w.Code != http.StatusCreated {
+				t.Errorf("render() status = %v, want %v", w.Code, http.StatusCreated)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("render() Content-Type = %v, want %v", got, tt.wantContentType)
+			}
+			if !c.replied || c.replyCode != resp.Code || c.replyMsg != resp.Message {
+				t.Errorf("render() reply = %v, %v, %v, want true, %v, %v", c.replied, c.replyCode, c.replyMsg, resp.Code, resp.Message)
+			}
+		})
+	}
+}
+
+func TestProtobufEncoder(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "response as struct",
+			v:    &Response{Code: "0", Message: "ok", Data: map[string]interface{}{"id": 1}},
+			want: map[string]interface{}{"code": "0", "message": "ok", "data": map[string]interface{}{"id": 1.0}, "time": "0001-01-01T00:00:00Z"},
+		},
+		{
+			name: "proto data of response",
+			v:    &Response{Code: "0", Data: structpb.NewStringValue("hi")},
+			want: map[string]interface{}{"code": "0", "data": "hi", "time": "0001-01-01T00:00:00Z"},
+		},
+		{
+			name: "proto message",
+			v:    structpb.NewStringValue("hi"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (protobufEncoder{}).Encode(&buf, tt.v); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if tt.want == nil {
+				want, _ := proto.Marshal(tt.v.(proto.Message))
+				if !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("Encode() = %v, want %v", buf.Bytes(), want)
+				}
+				return
+			}
+			s := &structpb.Struct{}
+			if err := proto.Unmarshal(buf.Bytes(), s); err != nil {
+				t.Fatalf("proto.Unmarshal() error = %v", err)
+			}
+			got, _ := json.Marshal(s.AsMap())
+			want, _ := json.Marshal(tt.want)
+			if string(got) != string(want) {
+				t.Errorf("Encode() = %s, want %s", got, want)
+			}
+		})
+	}
+}
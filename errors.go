@@ -0,0 +1,318 @@
+package frame
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Translations user reply translations, language => reply, eg: {"zh": "用户不存在"}
+type Translations map[string]string
+
+// Code business error code, declare it once with RegisterCode
+type Code struct {
+	Code         string       `json:"code"`
+	HTTPStatus   int          `json:"http_status"`
+	Reply        string       `json:"reply"`
+	Translations Translations `json:"translations,omitempty"`
+}
+
+// frame built-in codes, they are not registered so RegisterCode can still declare the same code strings
+var (
+	ErrInvalidParams = newCode("400", http.StatusBadRequest, "invalid request params", Translations{"zh": "请求参数错误"})
+	ErrInternal      = newCode("500", http.StatusInternalServerError, "internal server error", Translations{"zh": "服务器内部错误"})
+	ErrTimeout       = newCode("504", http.StatusGatewayTimeout, "request timeout", Translations{"zh": "请求超时"})
+)
+
+var builtinCodes = []*Code{ErrInvalidParams, ErrInternal, ErrTimeout}
+
+type codeRegistry struct {
+	sync.RWMutex
+	m map[string]*Code
+}
+
+var codes = &codeRegistry{m: map[string]*Code{}}
+
+// RegisterCode declare business error code, panic when code is registered twice.
+// httpStatus is used by ctx.Error, 0 means http 200.
+// eg: var ErrUserNotFound = frame.RegisterCode("U0001", http.StatusNotFound, "user not found", frame.Translations{"zh": "用户不存在"})
+func RegisterCode(code string, httpStatus int, reply string, translations ...Translations) *Code {
+	c := newCode(code, httpStatus, reply, translations...)
+	codes.Lock()
+	defer codes.Unlock()
+	if _, ok := codes.m[code]; ok {
+		panic(fmt.Sprintf("error code %s is registered twice", code))
+	}
+	codes.m[code] = c
+	return c
+}
+
+func newCode(code string, httpStatus int, reply string, translations ...Translations) *Code {
+	c := &Code{Code: code, HTTPStatus: httpStatus, Reply: reply, Translations: Translations{}}
+	for _, t := range translations {
+		for lang, r := range t {
+			c.Translations[strings.ToLower(lang)] = r
+		}
+	}
+	return c
+}
+
+// LookupCode return registered code
+func LookupCode(code string) (*Code, bool) {
+	codes.RLock()
+	defer codes.RUnlock()
+	c, ok := codes.m[code]
+	return c, ok
+}
+
+// Codes return all registered codes and the built-in codes not registered again, sorted by code
+func Codes() []*Code {
+	codes.RLock()
+	list := make([]*Code, 0, len(codes.m)+len(builtinCodes))
+	for _, c := range codes.m {
+		list = append(list, c)
+	}
+	for _, c := range builtinCodes {
+		if _, ok := codes.m[c.Code]; !ok {
+			list = append(list, c)
+		}
+	}
+	codes.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// ExportCodesJSON export registered codes as json array for frontend
+func ExportCodesJSON() ([]byte, error) {
+	return json.MarshalIndent(Codes(), "", "  ")
+}
+
+// ExportCodesMarkdown export registered codes as markdown table
+func ExportCodesMarkdown() string {
+	list := Codes()
+	// translation languages as columns
+	langSet := map[string]bool{}
+	for _, c := range list {
+		for lang := range c.Translations {
+			langSet[lang] = true
+		}
+	}
+	langs := make([]string, 0, len(langSet))
+	for lang := range langSet {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	var b strings.Builder
+	b.WriteString("| code | http status | reply |")
+	for _, lang := range langs {
+		b.WriteString(" " + lang + " |")
+	}
+	b.WriteString("\n|------|------|------|")
+	for range langs {
+		b.WriteString("------|")
+	}
+	b.WriteString("\n")
+	for _, c := range list {
+		b.WriteString("| " + mdEscape(c.Code) + " | " + strconv.Itoa(c.getHTTPStatus()) + " | " + mdEscape(c.Reply) + " |")
+		for _, lang := range langs {
+			b.WriteString(" " + mdEscape(c.Translations[lang]) + " |")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func mdEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func (c *Code) getHTTPStatus() int {
+	if c.HTTPStatus <= 0 {
+		return http.StatusOK
+	}
+	return c.HTTPStatus
+}
+
+// reply return reply in the first matched language, eg: zh-cn match zh
+func (c *Code) reply(langs []string) string {
+	for _, lang := range langs {
+		if r, ok := c.Translations[lang]; ok {
+			return r
+		}
+		if i := strings.Index(lang, "-"); i > 0 {
+			if r, ok := c.Translations[lang[:i]]; ok {
+				return r
+			}
+		}
+	}
+	return c.Reply
+}
+
+// New return an error of this code, real is only logged
+func (c *Code) New(real ...string) *Error {
+	return &Error{code: c, real: strings.Join(real, " ")}
+}
+
+// Wrap return an error of this code wrapping err, err is only logged
+func (c *Code) Wrap(err error) *Error {
+	return &Error{code: c, err: err}
+}
+
+// WithDetail return an error of this code with user visible detail
+func (c *Code) WithDetail(detail string) *Error {
+	return &Error{code: c, detail: detail}
+}
+
+// Error frame error, implement ErrorMsg and error
+type Error struct {
+	code   *Code
+	err    error
+	real   string
+	detail string
+}
+
+// Wrap return a copy wrapping err
+func (e *Error) Wrap(err error) *Error {
+	cp := *e
+	cp.err = err
+	return &cp
+}
+
+// WithDetail return a copy with user visible detail, eg: "id=3"
+func (e *Error) WithDetail(detail string) *Error {
+	cp := *e
+	cp.detail = detail
+	return &cp
+}
+
+// GetCode error code
+func (e *Error) GetCode() string {
+	return e.code.Code
+}
+
+// GetReal real error message, only log
+func (e *Error) GetReal() string {
+	var list []string
+	if e.real != "" {
+		list = append(list, e.real)
+	}
+	if e.err != nil {
+		list = append(list, e.err.Error())
+	}
+	if len(list) <= 0 {
+		return e.code.Reply
+	}
+	return strings.Join(list, ": ")
+}
+
+// GetReply user reply in default language
+func (e *Error) GetReply() string {
+	return e.withDetail(e.code.Reply)
+}
+
+// GetReplyLang user reply in the first matched language of langs
+func (e *Error) GetReplyLang(langs ...string) string {
+	return e.withDetail(e.code.reply(langs))
+}
+
+// GetDetail user visible detail
+func (e *Error) GetDetail() string {
+	return e.detail
+}
+
+// GetHTTPStatus http status declared by RegisterCode
+func (e *Error) GetHTTPStatus() int {
+	return e.code.getHTTPStatus()
+}
+
+func (e *Error) withDetail(reply string) string {
+	if e.detail == "" {
+		return reply
+	}
+	return reply + ": " + e.detail
+}
+
+// Error implement error
+func (e *Error) Error() string {
+	return e.code.Code + ": " + e.GetReal()
+}
+
+// Unwrap return wrapped error
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is errors.Is match errors of the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.code == e.code
+}
+
+// httpStatusError ErrorMsg with http status
+type httpStatusError interface {
+	GetHTTPStatus() int
+}
+
+// localizedError ErrorMsg with translated reply
+type localizedError interface {
+	GetReplyLang(langs ...string) string
+}
+
+// errorStatus http status declared by the error, eg: *Error of a Code, other errors are replied with 200
+func errorStatus(errMsg ErrorMsg) int {
+	if e, ok := errMsg.(httpStatusError); ok {
+		return e.GetHTTPStatus()
+	}
+	return http.StatusOK
+}
+
+// replyOf user reply of errMsg in the request language
+func (c *Context) replyOf(errMsg ErrorMsg) string {
+	langs := c.acceptLanguages()
+	if e, ok := errMsg.(localizedError); ok {
+		return e.GetReplyLang(langs...)
+	}
+	if code, ok := LookupCode(errMsg.GetCode()); ok && errMsg.GetReply() == code.Reply {
+		return code.reply(langs)
+	}
+	return errMsg.GetReply()
+}
+
+// acceptLanguages return Accept-Language languages ordered by q, eg: zh-CN,zh;q=0.9,en;q=0.8 => [zh-cn zh en]
+func (c *Context) acceptLanguages() []string {
+	if c.Gtx == nil || c.Gtx.Request == nil {
+		return nil
+	}
+	type langQ struct {
+		lang string
+		q    float64
+	}
+	var list []langQ
+	for _, part := range strings.Split(c.Gtx.GetHeader("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v := strings.TrimSpace(f); strings.HasPrefix(v, "q=") {
+				if n, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = n
+				}
+			}
+		}
+		list = append(list, langQ{lang: lang, q: q})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+	langs := make([]string, 0, len(list))
+	for _, l := range list {
+		langs = append(langs, l.lang)
+	}
+	return langs
+}
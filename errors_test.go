@@ -0,0 +1,127 @@
+package frame
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerTestCode register a code for the test and remove it afterwards
+func registerTestCode(t *testing.T, code string, httpStatus int, reply string, translations ...Translations) *Code {
+	c := RegisterCode(code, httpStatus, reply, translations...)
+	t.Cleanup(func() {
+		codes.Lock()
+		delete(codes.m, code)
+		codes.Unlock()
+	})
+	return c
+}
+
+// newLangContext context of a request with the Accept-Language header
+func newLangContext(acceptLanguage string) *Context {
+	gtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	gtx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptLanguage != "" {
+		gtx.Request.Header.Set("Accept-Language", acceptLanguage)
+	}
+	return &Context{Gtx: gtx}
+}
+
+func TestErrorStatus(t *testing.T) {
+	notFound := registerTestCode(t, "T404", http.StatusNotFound, "not found")
+	noStatus := registerTestCode(t, "T0", 0, "no status")
+	tests := []struct {
+		name   string
+		errMsg ErrorMsg
+		want   int
+	}{
+		{name: "registered code", errMsg: notFound.New(), want: http.StatusNotFound},
+		{name: "wrapped with detail", errMsg: notFound.Wrap(errors.New("db")).WithDetail("id=3"), want: http.StatusNotFound},
+		{name: "code without status", errMsg: noStatus.New(), want: http.StatusOK},
+		{name: "built-in code", errMsg: ErrTimeout.New(), want: http.StatusGatewayTimeout},
+		{name: "plain error with a registered code string", errMsg: newErrorMsg("T404", "not found", nil), want: http.StatusOK},
+		{name: "plain error with a built-in code string", errMsg: newErrorMsg("500", "oops", nil), want: http.StatusOK},
+		{name: "bind error", errMsg: &bindError{reply: "name is required"}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorStatus(tt.errMsg); got != tt.want {
+				t.Errorf("errorStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplyOf(t *testing.T) {
+	notFound := registerTestCode(t, "T404", http.StatusNotFound, "user not found", Translations{"zh": "用户不存在"})
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		errMsg         ErrorMsg
+		want           string
+	}{
+		{name: "default language", errMsg: notFound.New(), want: "user not found"},
+		{name: "translation", acceptLanguage: "zh", errMsg: notFound.New(), want: "用户不存在"},
+		{name: "region falls back to language", acceptLanguage: "zh-CN", errMsg: notFound.New(), want: "用户不存在"},
+		{name: "q order", acceptLanguage: "en;q=0.5, zh;q=0.9", errMsg: notFound.New(), want: "用户不存在"},
+		{name: "unknown language", acceptLanguage: "fr", errMsg: notFound.New(), want: "user not found"},
+		{name: "detail", acceptLanguage: "zh", errMsg: notFound.WithDetail("id=3"), want: "用户不存在: id=3"},
+		{name: "built-in code", acceptLanguage: "zh", errMsg: ErrInvalidParams.New(), want: "请求参数错误"},
+		{name: "plain error with the code reply", acceptLanguage: "zh", errMsg: newErrorMsg("T404", "user not found", nil), want: "用户不存在"},
+		{name: "plain error with its own reply", acceptLanguage: "zh", errMsg: newErrorMsg("T404", "user is disabled", nil), want: "user is disabled"},
+		{name: "plain error of unknown code", acceptLanguage: "zh", errMsg: newErrorMsg("X1", "oops", nil), want: "oops"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newLangContext(tt.acceptLanguage).replyOf(tt.errMsg); got != tt.want {
+				t.Errorf("replyOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterBuiltinCodeString(t *testing.T) {
+	c := registerTestCode(t, ErrInvalidParams.Code, http.StatusUnprocessableEntity, "business 400")
+	if got, ok := LookupCode(ErrInvalidParams.Code); !ok || got != c {
+		t.Errorf("LookupCode() = %v, %v, want the registered code", got, ok)
+	}
+	if got := errorStatus(ErrInvalidParams.New()); got != http.StatusBadRequest {
+		t.Errorf("errorStatus() of the built-in code = %v, want %v", got, http.StatusBadRequest)
+	}
+	var n int
+	for _, code := range Codes() {
+		if code.Code == ErrInvalidParams.Code {
+			n++
+			if code != c {
+				t.Errorf("Codes() contains the built-in code shadowed by the registered one")
+			}
+		}
+	}
+	if n != 1 {
+		t.Errorf("Codes() contains code %s %d times, want 1", ErrInvalidParams.Code, n)
+	}
+}
+
+func TestAcceptLanguages(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           []string
+	}{
+		{name: "empty", acceptLanguage: "", want: []string{}},
+		{name: "lower case", acceptLanguage: "zh-CN", want: []string{"zh-cn"}},
+		{name: "sorted by q", acceptLanguage: "en;q=0.3, zh-CN, ja;q=0.8", want: []string{"zh-cn", "ja", "en"}},
+		{name: "wildcard ignored", acceptLanguage: "*, en", want: []string{"en"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newLangContext(tt.acceptLanguage).acceptLanguages(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("acceptLanguages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
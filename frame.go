@@ -2,13 +2,15 @@ package frame
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imroc/req/v3"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
 var (
@@ -26,9 +28,13 @@ func getLogConf() *Config {
 
 func getConfig(configPath ...string) (*ConfigManager, *Config) {
 	cm, cf := LoadConfig(configPath...)
+	setTraceIDConfig(cf.TraceID)
+	setRedactConfig(cf.Redact)
+	newLogOutputs(cf)
 	if initLoadConf == 0 {
 		defaultLogLevel = cf.LogLevel
 		defaultLogMode = cf.LogMode
+		applyLogConfig(cf)
 		initLoadConf = 1
 	}
 
@@ -43,6 +49,15 @@ type App struct {
 	dbClients     *DBMultiClient
 	redisClients  *RedisMultiClient
 	log           *logrus.Logger
+	lifecycle     lifecycle
+	contextPool   sync.Pool
+	servers       map[string]*Server
+	serverNames   []string
+	defaultServer *Server
+	routes        routeTable
+	grpcServer    *grpc.Server
+	metrics       *metrics
+	logQueue      *logQueue // access log workers
 	*logrus.Entry
 }
 
@@ -54,34 +69,48 @@ func New(configPath ...string) *App {
 	return e
 }
 
-// Run engin run
+// Run engin run, block until SIGINT/SIGTERM or server error, then graceful shutdown
 // ":8080"
 func (e *App) Run() error {
-	go e.metricRun()
-	return e.serverRun()
+	if err := e.runStartHooks(context.Background()); err != nil {
+		return err
+	}
+	errCh := make(chan error, len(e.servers)+2)
+	e.metricRun(errCh)
+	e.serverRun(errCh)
+	e.grpcRun(errCh)
+	runErr := waitSignal(errCh)
+	return errors.Join(runErr, e.Shutdown())
 }
 
-func (e *App) metricRun() {
-	if e.config.EnableMetric && e.config.HTTPServer.Enable {
+func (e *App) metricRun(errCh chan<- error) {
+	if e.config.EnableMetric && (e.config.HTTPServer.Enable || e.config.GRPCServer.Enable) {
 		// metrics
-		port := e.getMetricPort()
-		logrus.Infof("%s server listen %s\n", defaultMetricName, port)
-		http.Handle(defaultMetricPath, promhttp.Handler())
-		if err := http.ListenAndServe(port, nil); err != nil {
-			logrus.Fatalln(err.Error())
+		mux := http.NewServeMux()
+		mux.Handle(defaultMetricPath, e.metrics.handler())
+		if e.config.HTTPServer.OpenAPI.Enable && isMetricName(e.config.HTTPServer.OpenAPI.getServer()) {
+			e.mountOpenAPI(mux, nil)
+		}
+		if e.config.Log.Admin.Enable && isMetricName(e.config.Log.Admin.getServer()) {
+			e.mountLogAdmin(mux, nil)
 		}
+		e.serve(defaultMetricName, &http.Server{Addr: e.getMetricPort(), Handler: mux}, errCh)
 	}
 }
-func (e *App) serverRun() error {
+
+func (e *App) serverRun(errCh chan<- error) {
 	if e.config.HTTPServer.Enable {
-		// server port
-		port := e.getServerPort()
-		logrus.Infof("server listen %s\n", port)
-		if err := e.Engine.Run(port); err != nil {
-			logrus.Fatalln(err.Error())
+		if e.config.HTTPServer.OpenAPI.Enable && !isMetricName(e.config.HTTPServer.OpenAPI.getServer()) {
+			e.mountOpenAPI(nil, e.Server(e.config.HTTPServer.OpenAPI.getServer()))
+		}
+		if e.config.Log.Admin.Enable && !isMetricName(e.config.Log.Admin.getServer()) {
+			e.mountLogAdmin(nil, e.Server(e.config.Log.Admin.getServer()))
+		}
+		// one listener per business server config
+		for _, s := range e.Servers() {
+			e.serve(s.name, &http.Server{Addr: s.port, Handler: s.Engine}, errCh)
 		}
 	}
-	return nil
 }
 
 // NewLogEntry new log entry
@@ -89,10 +118,6 @@ func (e *App) NewLogEntry() {
 	e.Entry = e.log.WithField(TraceIDKey, generateTraceID(e.config.Project))
 }
 
-func (e *App) getServerPort() string {
-	return e.config.getServerPort()
-}
-
 func (e *App) getMetricPort() string {
 	return e.config.getMetricPort()
 }
@@ -106,7 +131,16 @@ func newApp(configPath ...string) *App {
 	cm, ac := getConfig(configPath...)
 
 	// step 2:  log
-	logger := NewLogger(ac)
+	logger := NamedLogger(LoggerApp)
+	if ac.Log.Watch {
+		watchLogConfig(cm)
+	}
+
+	// metrics registry of this app
+	m := newMetrics(ac)
+
+	// tracing, before clients are created
+	newTracing(ac)
 
 	// step 3: mysql
 	newMySQLServers(ac)
@@ -117,13 +151,17 @@ func newApp(configPath ...string) *App {
 	redisConns := GetRedisConn()
 
 	e := &App{
-		Engine:        defaultEngine(),
 		log:           logger,
 		config:        ac,
 		configManager: cm,
 		dbClients:     mysqlConns,
 		redisClients:  redisConns,
+		metrics:       m,
+		logQueue:      newAccessLogQueue(ac, m),
 	}
+	// step 5: http servers
+	e.newServers()
+	e.Engine = e.defaultServer.Engine
 
 	// common trace id
 	e.NewLogEntry()
@@ -132,6 +170,12 @@ func newApp(configPath ...string) *App {
 	}
 	e.Use(TraceFunc())
 	e.Use(LoggerFunc())
+	if !e.config.HTTPServer.Recovery.Disable {
+		e.Use(RecoveryFunc())
+	}
+	if timeout := e.config.getRequestTimeout(); timeout > 0 {
+		e.Use(TimeoutFunc(timeout))
+	}
 
 	// table auto migrate
 	e.autoMigrateMysql(configPath...)
@@ -139,20 +183,36 @@ func newApp(configPath ...string) *App {
 }
 
 func defaultEngine() *gin.Engine {
-	r := gin.Default()
+	// panic is recovered by RecoveryFunc, gin log is discarded
+	r := gin.New()
 	return r
 }
 
 func (e *App) createContext(c *gin.Context) *Context {
-	// set http client
-	return &Context{
-		Gtx:           c,
-		config:        e.config,
-		configManager: e.configManager,
-		redisClients:  e.redisClients,
-		dbClients:     e.dbClients,
-		Entry:         e.getLogEntry(c),
-		httpClient:    e.getHTTPClient(c),
+	ctx, _ := e.contextPool.Get().(*Context)
+	if ctx == nil {
+		ctx = &Context{}
+	}
+	ctx.Gtx = c
+	ctx.config = e.config
+	ctx.configManager = e.configManager
+	ctx.redisClients = e.redisClients
+	ctx.dbClients = e.dbClients
+	ctx.metrics = e.metrics
+	ctx.logQueue = e.logQueue
+	ctx.Entry = e.getLogEntry(c)
+	return ctx
+}
+
+// contextHandler create one frame context per request, recycle it when the request is finished
+func (e *App) contextHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := e.createContext(c)
+		c.Set(frameContextKey, ctx)
+		c.Next()
+		c.Set(frameContextKey, nil)
+		ctx.reset()
+		e.contextPool.Put(ctx)
 	}
 }
 
@@ -165,8 +225,9 @@ func NewContextNoGin(configPath ...string) *Context {
 		configManager: cm,
 		redisClients:  GetRedisConn(),
 		dbClients:     GetMySQLConn(),
-		Entry:         NewLogger(c).WithField(TraceIDKey, traceID),
-		httpClient:    getHTTPClient(c, traceID),
+		Entry:         traceEntry(LoggerApp, traceID),
+		httpClient:    getHTTPClient(c, nil, traceID), // no app, http client metrics are off
+		traceID:       traceID,
 	}
 }
 
@@ -177,19 +238,15 @@ func (e *App) autoMigrateMysql(configPath ...string) {
 }
 
 func (e *App) getTraceID(c *gin.Context) string {
-	return c.GetHeader(TraceIDKey)
+	traceID, _ := incomingTraceID(c.Request.Header)
+	return traceID
 }
 
 func (e *App) getLogEntry(c *gin.Context) *logrus.Entry {
-	return e.log.WithField(TraceIDKey, e.getTraceID(c))
+	return traceEntry(LoggerApp, e.getTraceID(c))
 }
 
-func (e *App) getHTTPClient(c *gin.Context) *req.Client {
-	traceID := c.GetHeader(TraceIDKey)
-	return getHTTPClient(e.config, traceID)
-}
-
-func getHTTPClient(conf *Config, traceID ...string) *req.Client {
+func getHTTPClient(conf *Config, m *metrics, traceID ...string) *req.Client {
 	tid := ""
 	if len(traceID) <= 0 {
 		tid = generateTraceID(conf.Project)
@@ -197,65 +254,117 @@ func getHTTPClient(conf *Config, traceID ...string) *req.Client {
 		tid = traceID[0]
 	}
 	rc := req.C()
-	rc = rc.SetCommonHeader(TraceIDKey, tid)
+	rc = rc.SetCommonHeader(getTraceIDConfig().getOutgoingHeader(), tid)
 	if !conf.HTTPClient.DisableReqLog {
 		rc = rc.OnAfterResponse(ReqLogMiddleware)
 	}
-	if conf.HTTPClient.EnableMetric {
-		rc = rc.OnAfterResponse(ReqMetricMiddleware)
+	if conf.HTTPClient.EnableMetric && m != nil {
+		rc = rc.OnAfterResponse(m.reqMiddleware)
+	}
+	if conf.Tracing.Enable {
+		rc = rc.WrapRoundTripFunc(tracingRoundTrip)
 	}
 	return rc
 }
 
-// GET get method
-func (e *App) GET(relativePath string, handler func(c *Context)) {
-	e.Engine.GET(relativePath, func(c *gin.Context) {
-		ctx := e.createContext(c)
-		handler(ctx)
-	})
+// getContext return the frame context of this request, create it on first use
+func (e *App) getContext(c *gin.Context) *Context {
+	if v, ok := c.Get(frameContextKey); ok {
+		if ctx, ok := v.(*Context); ok && ctx != nil {
+			return ctx
+		}
+	}
+	// not created by contextHandler, eg: gin.Context.Copy, never put back to the pool
+	ctx := &Context{
+		Gtx:           c,
+		config:        e.config,
+		configManager: e.configManager,
+		redisClients:  e.redisClients,
+		dbClients:     e.dbClients,
+		metrics:       e.metrics,
+		logQueue:      e.logQueue,
+		Entry:         e.getLogEntry(c),
+	}
+	c.Set(frameContextKey, ctx)
+	return ctx
 }
 
-// POST post method
-func (e *App) POST(relativePath string, handler func(c *Context)) {
-	e.Engine.POST(relativePath, func(c *gin.Context) {
-		ctx := e.createContext(c)
-		handler(ctx)
-	})
+// Group create router group on the default server
+// eg: v1 := app.Group("/api/v1", AuthFunc())
+func (e *App) Group(relativePath string, handlers ...HandlerFunc) *RouterGroup {
+	return e.defaultServer.Group(relativePath, handlers...)
 }
 
-// PUT http put method
-func (e *App) PUT(relativePath string, handler func(c *Context)) {
-	e.Engine.PUT(relativePath, func(c *gin.Context) {
-		ctx := e.createContext(c)
-		handler(ctx)
-	})
+// Handle register handlers with the given http method on the default server
+func (e *App) Handle(httpMethod, relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.Handle(httpMethod, relativePath, handlers...)
 }
 
-// PATCH  http patch method
-func (e *App) PATCH(relativePath string, handler func(c *Context)) {
-	e.Engine.PATCH(relativePath, func(c *gin.Context) {
-		ctx := e.createContext(c)
-		handler(ctx)
-	})
+// GET get method, register on the default server
+func (e *App) GET(relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.GET(relativePath, handlers...)
 }
 
-// DELETE http delete method
-func (e *App) DELETE(relativePath string, handler func(c *Context)) {
-	e.Engine.DELETE(relativePath, func(c *gin.Context) {
-		ctx := e.createContext(c)
-		handler(ctx)
-	})
+// POST post method, register on the default server
+func (e *App) POST(relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.POST(relativePath, handlers...)
 }
 
-// HEAD http head method
-func (e *App) HEAD(relativePath string, handler func(c *Context)) {
-	e.Engine.HEAD(relativePath, func(c *gin.Context) {
-		ctx := e.createContext(c)
-		handler(ctx)
-	})
+// PUT http put method, register on the default server
+func (e *App) PUT(relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.PUT(relativePath, handlers...)
+}
+
+// PATCH  http patch method, register on the default server
+func (e *App) PATCH(relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.PATCH(relativePath, handlers...)
+}
+
+// DELETE http delete method, register on the default server
+func (e *App) DELETE(relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.DELETE(relativePath, handlers...)
+}
+
+// HEAD http head method, register on the default server
+func (e *App) HEAD(relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.HEAD(relativePath, handlers...)
+}
+
+// OPTIONS http options method, register on the default server
+func (e *App) OPTIONS(relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.OPTIONS(relativePath, handlers...)
+}
+
+// Any register handlers for all http methods on the default server
+func (e *App) Any(relativePath string, handlers ...HandlerFunc) {
+	e.defaultServer.Any(relativePath, handlers...)
+}
+
+// NoRoute handlers for 404 on the default server
+func (e *App) NoRoute(handlers ...HandlerFunc) {
+	e.defaultServer.NoRoute(handlers...)
+}
+
+// NoMethod handlers for 405 on the default server
+func (e *App) NoMethod(handlers ...HandlerFunc) {
+	e.defaultServer.NoMethod(handlers...)
 }
 
 func getTraceIDFromContext(ctx context.Context) string {
-	traceID := ctx.Value(TraceIDKey)
-	return traceID.(string)
+	if ctx == nil {
+		return ""
+	}
+	traceID, _ := ctx.Value(TraceIDKey).(string)
+	return traceID
+}
+
+// bindRequestContext requests sent without an explicit context use the context returned by ctx when sent,
+// they are cancelled together with the incoming request
+func bindRequestContext(ctx func() context.Context) req.RequestMiddleware {
+	return func(_ *req.Client, r *req.Request) error {
+		if r.Context() == context.Background() {
+			r.SetContext(ctx())
+		}
+		return nil
+	}
 }
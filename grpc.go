@@ -0,0 +1,301 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// GRPCServerConfig grpc server config
+type GRPCServerConfig struct {
+	Enable        bool   `json:"enable"`
+	Port          string `json:"port"`                                                                  // eg: :9000
+	DisableReqLog bool   `json:"disable_req_log" yaml:"disable_req_log" mapstructure:"disable_req_log"` // default enable
+}
+
+// Validate check grpc server config
+func (gc GRPCServerConfig) Validate() []error {
+	if !gc.Enable {
+		return nil
+	}
+	port, _ := strconv.Atoi(strings.TrimPrefix(gc.Port, ":"))
+	if !strings.HasPrefix(gc.Port, ":") || port <= 0 || port > 65535 {
+		return []error{errors.New("please fill in the correct grpc_server.port in the configuration file, port range 1 ~ 65535, eg :9000")}
+	}
+	return nil
+}
+
+// contextKey std context key of the frame context
+type contextKey struct{}
+
+// FromContext return the frame context of grpc handlers, eg: c := frame.FromContext(ctx); c.GetDB()
+// an http *Context is returned as is, nil when ctx has no frame context
+func FromContext(ctx context.Context) *Context {
+	if c, ok := ctx.(*Context); ok {
+		return c
+	}
+	c, _ := ctx.Value(contextKey{}).(*Context)
+	return c
+}
+
+// GRPCServer return the grpc server to register services, eg: pb.RegisterUserServer(app.GRPCServer(), &UserService{}).
+// The server is created on first call with frame interceptors (trace id, access log, metrics, panic recovery)
+// followed by opts, opts of later calls are ignored.
+func (e *App) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	e.lifecycle.Lock()
+	defer e.lifecycle.Unlock()
+	if e.grpcServer == nil {
+		opts = append([]grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(e.grpcUnaryInterceptor()),
+			grpc.ChainStreamInterceptor(e.grpcStreamInterceptor()),
+		}, opts...)
+		e.grpcServer = grpc.NewServer(opts...)
+	}
+	return e.grpcServer
+}
+
+func (e *App) grpcRun(errCh chan<- error) {
+	if !e.config.GRPCServer.Enable {
+		return
+	}
+	srv := e.GRPCServer()
+	port := e.config.GRPCServer.Port
+	go func() {
+		lis, err := net.Listen("tcp", port)
+		if err != nil {
+			errCh <- fmt.Errorf("grpc server: %w", err)
+			return
+		}
+		logrus.Infof("grpc server listen %s\n", port)
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			errCh <- fmt.Errorf("grpc server: %w", err)
+		}
+	}()
+}
+
+// grpcShutdown graceful stop, force stop when ctx is done
+func (e *App) grpcShutdown(ctx context.Context) error {
+	e.lifecycle.Lock()
+	srv := e.grpcServer
+	e.lifecycle.Unlock()
+	if srv == nil || !e.config.GRPCServer.Enable {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		return fmt.Errorf("shutdown grpc server %s: %w", e.config.GRPCServer.Port, ctx.Err())
+	}
+}
+
+// newGRPCContext frame context of a grpc call, trace id is read from incoming metadata or generated,
+// it is sent back in the response header and propagated to outgoing calls made with ctx.
+// An InvalidArgument error is returned when a malformed trace id is rejected by trace_id.invalid_policy.
+func (e *App) newGRPCContext(ctx context.Context, method string) (*Context, context.Context, trace.Span, error) {
+	var span trace.Span
+	if e.config.Tracing.Enable {
+		ctx, span = startGRPCSpan(ctx, method)
+	}
+	conf := getTraceIDConfig()
+	traceID, invalid := incomingGRPCTraceID(ctx, conf)
+	if traceID == "" {
+		traceID = spanTraceID(span)
+	}
+	if traceID == "" {
+		traceID = generateTraceID(e.config.Project)
+	}
+	if span != nil {
+		span.SetAttributes(attribute.String(frameTraceIDAttr, traceID))
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(conf.getOutgoingHeader(), traceID))
+	ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(conf.getOutgoingHeader()), traceID)
+	ctx = context.WithValue(ctx, TraceIDKey, traceID)
+	c := &Context{
+		config:        e.config,
+		configManager: e.configManager,
+		redisClients:  e.redisClients,
+		dbClients:     e.dbClients,
+		metrics:       e.metrics,
+		logQueue:      e.logQueue,
+		Entry:         traceEntry(LoggerApp, traceID),
+		traceID:       traceID,
+	}
+	ctx = context.WithValue(ctx, contextKey{}, c)
+	c.ctx = ctx
+	if invalid {
+		if conf.InvalidPolicy == TraceIDInvalidReject {
+			return c, ctx, span, status.Error(grpccodes.InvalidArgument, "invalid trace id")
+		}
+		c.Warnln("malformed incoming trace id is replaced")
+	}
+	return c, ctx, span, nil
+}
+
+// incomingGRPCTraceID first valid trace id of the incoming metadata, invalid is true when a malformed one is found
+func incomingGRPCTraceID(ctx context.Context, conf TraceIDConfig) (traceID string, invalid bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, name := range conf.incomingHeaders() {
+		v := md.Get(name)
+		if len(v) == 0 || v[0] == "" {
+			continue
+		}
+		if !conf.valid(v[0]) {
+			return "", true
+		}
+		return v[0], false
+	}
+	return "", false
+}
+
+func (e *App) grpcUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		c, ctx, span, err := e.newGRPCContext(ctx, info.FullMethod)
+		startTime := time.Now()
+		defer func() {
+			if r := recover(); r != nil {
+				err = c.grpcRecover(info.FullMethod, r)
+			}
+			endGRPCSpan(span, err)
+			c.grpcAccessLog(info.FullMethod, startTime, err, req, resp)
+		}()
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (e *App) grpcStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		c, ctx, span, err := e.newGRPCContext(ss.Context(), info.FullMethod)
+		startTime := time.Now()
+		defer func() {
+			if r := recover(); r != nil {
+				err = c.grpcRecover(info.FullMethod, r)
+			}
+			endGRPCSpan(span, err)
+			c.grpcAccessLog(info.FullMethod, startTime, err, nil, nil)
+		}()
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func endGRPCSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	st := status.Convert(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(st.Code())))
+	if err != nil {
+		spanError(span, err)
+	}
+	span.End()
+}
+
+// serverStream grpc server stream with the frame context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcRecover log panic and return internal error
+func (c *Context) grpcRecover(method string, r interface{}) error {
+	entry := c.WithField("panic", fmt.Sprint(r))
+	if !c.config.HTTPServer.Recovery.DisableStack {
+		entry = entry.WithField("stack", string(debug.Stack()))
+	}
+	entry.Error("grpc panic recovered")
+	if c.config.EnableMetric {
+		c.metrics.requestPanicCounter.WithLabelValues(method, grpcMethod).Inc()
+	}
+	return status.Error(grpccodes.Internal, ErrInternal.Reply)
+}
+
+// grpcAccessLog record metrics inline and write access log in the same format as http through the access log queue,
+// request/response are logged for unary calls
+func (c *Context) grpcAccessLog(method string, startTime time.Time, err error, req, resp interface{}) {
+	elapsed := time.Since(startTime)
+	duration := elapsed.Milliseconds()
+	st := status.Convert(err)
+	code := st.Code().String()
+	if c.config.EnableMetric {
+		c.metrics.grpcDuration.WithLabelValues(method, code).Observe(elapsed.Seconds())
+		c.metrics.grpcCounter.WithLabelValues(method, code).Inc()
+	}
+	if c.config.GRPCServer.DisableReqLog {
+		return
+	}
+	if err != nil {
+		c.printRealMsgLog(err.Error())
+	}
+	// messages are encoded before the call returns, they may be reused afterwards,
+	// bodies are truncated by access_log.max_req_body_bytes/max_resp_body_bytes like http
+	alc := c.config.AccessLog
+	reqBody, respBody := protoString(req, alc.getMaxReqBodyBytes()), protoString(resp, alc.getMaxRespBodyBytes())
+	c.submitAccessLog(func() {
+		reqLog := logBody{
+			TraceType:  TraceLogGRPC,
+			TraceID:    c.GetTraceID(),
+			Code:       code,
+			StatusCode: int(st.Code()),
+			Duration:   duration,
+			Msg:        st.Message(),
+			Path:       method,
+			Extra: reqLogExtra{
+				Req:  reqLogBody{Body: reqBody},
+				Resp: respLogBody{Body: respBody},
+			},
+		}
+		getRedactor().logBody(&reqLog)
+		accessEntry(c.Entry).WithField(TraceLogKey, reqLog).Info("")
+	})
+}
+
+// protoString json of the message, at most limit bytes
+func protoString(v interface{}, limit int) string {
+	msg, ok := v.(proto.Message)
+	if !ok || !msg.ProtoReflect().IsValid() {
+		return ""
+	}
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return ""
+	}
+	if len(b) > limit {
+		return string(b[:limit]) + truncatedMarker
+	}
+	return string(b)
+}
@@ -0,0 +1,29 @@
+package frame
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestProtoString(t *testing.T) {
+	tests := []struct {
+		name  string
+		v     interface{}
+		limit int
+		want  string
+	}{
+		{name: "whole message", v: structpb.NewStringValue("hello"), limit: 64, want: `"hello"`},
+		{name: "limit equals size", v: structpb.NewStringValue("hello"), limit: 7, want: `"hello"`},
+		{name: "truncated", v: structpb.NewStringValue("hello"), limit: 3, want: `"he` + truncatedMarker},
+		{name: "nil message", v: (*structpb.Value)(nil), limit: 64, want: ""},
+		{name: "not a message", v: "hello", limit: 64, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := protoString(tt.v, tt.limit); got != tt.want {
+				t.Errorf("protoString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
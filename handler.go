@@ -0,0 +1,290 @@
+package frame
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+// Handle typed handler adapter, bind path/query/header/body into Req, validate it by `binding` tags,
+// then reply Success with the result or Error on failure.
+// Query, header and path values only fill fields with an explicit form, header or uri tag,
+// path params are bound last and can not be overridden by the query or body.
+// Bind and validation errors reply http 400 with localized messages picked by Accept-Language.
+// eg: app.POST("/users/:id", frame.Handle(UpdateUser))
+//
+//	type UpdateUserReq struct {
+//		ID    int    `uri:"id" binding:"required"`
+//		Token string `header:"X-Token"`
+//		Name  string `json:"name" binding:"required,max=32"`
+//	}
+//
+//	func UpdateUser(c *frame.Context, req *UpdateUserReq) (*User, frame.ErrorMsg)
+func Handle[Req any, Resp any](fn func(*Context, *Req) (*Resp, ErrorMsg)) HandlerFunc {
+	meta := handlerMeta{req: reflect.TypeOf((*Req)(nil)).Elem(), resp: reflect.TypeOf((*Resp)(nil)).Elem(), fn: handlerName(fn)}
+	return func(c *Context) {
+		if c.describe != nil {
+			*c.describe = meta
+			return
+		}
+		req := new(Req)
+		if errMsg := c.bindRequest(req); errMsg != nil {
+			c.HTTPError(http.StatusBadRequest, errMsg)
+			return
+		}
+		resp, errMsg := fn(c, req)
+		if !isNilErrorMsg(errMsg) {
+			c.Error(errMsg)
+			return
+		}
+		c.Success(resp)
+	}
+}
+
+// bindRequest bind query, header, body and path into obj, then validate it
+func (c *Context) bindRequest(obj interface{}) ErrorMsg {
+	r := c.Gtx.Request
+	if err := binding.MapFormWithTag(obj, taggedForm(obj, r.URL.Query(), "form"), "form"); err != nil {
+		return newBindError(c, err)
+	}
+	if err := binding.MapFormWithTag(obj, taggedForm(obj, headerForm(r.Header), "header"), "header"); err != nil {
+		return newBindError(c, err)
+	}
+	if err := bindBody(r, obj); err != nil {
+		return newBindError(c, err)
+	}
+	// path params last, they are final
+	if len(c.Gtx.Params) > 0 {
+		params := make(map[string][]string, len(c.Gtx.Params))
+		for _, p := range c.Gtx.Params {
+			params[p.Key] = []string{p.Value}
+		}
+		if err := binding.MapFormWithTag(obj, taggedForm(obj, params, "uri"), "uri"); err != nil {
+			return newBindError(c, err)
+		}
+	}
+	if err := validateRequest(obj); err != nil {
+		return newBindError(c, err)
+	}
+	return nil
+}
+
+// isNilErrorMsg nil or typed nil, eg: a nil *Error returned as ErrorMsg
+func isNilErrorMsg(errMsg ErrorMsg) bool {
+	if errMsg == nil {
+		return true
+	}
+	v := reflect.ValueOf(errMsg)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// taggedForm keep the values named by an explicit tag of obj,
+// gin falls back to the field name for untagged fields
+func taggedForm(obj interface{}, form map[string][]string, tag string) map[string][]string {
+	names := map[string]bool{}
+	collectTagNames(reflect.TypeOf(obj), tag, names, map[reflect.Type]bool{})
+	tagged := make(map[string][]string, len(names))
+	for k, v := range form {
+		if names[k] {
+			tagged[k] = v
+		}
+	}
+	return tagged
+}
+
+// collectTagNames names of tag in t and its nested structs
+func collectTagNames(t reflect.Type, tag string, names map[string]bool, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		if name != "" {
+			names[name] = true
+		}
+		collectTagNames(f.Type, tag, names, seen)
+	}
+}
+
+// headerForm header tag can be canonical or lower case, eg: X-Token or x-token
+func headerForm(h http.Header) map[string][]string {
+	form := make(map[string][]string, len(h)*2)
+	for k, v := range h {
+		form[textproto.CanonicalMIMEHeaderKey(k)] = v
+		form[strings.ToLower(k)] = v
+	}
+	return form
+}
+
+func bindBody(r *http.Request, obj interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case binding.MIMEPOSTForm:
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+		return binding.MapFormWithTag(obj, taggedForm(obj, r.PostForm, "form"), "form")
+	case binding.MIMEMultipartPOSTForm:
+		if err := r.ParseMultipartForm(defaultMultipartMemory); err != nil {
+			return err
+		}
+		return binding.MapFormWithTag(obj, taggedForm(obj, r.MultipartForm.Value, "form"), "form")
+	case binding.MIMEXML, binding.MIMEXML2:
+		return ignoreEOF(xml.NewDecoder(r.Body).Decode(obj))
+	default:
+		// default json
+		return ignoreEOF(json.NewDecoder(r.Body).Decode(obj))
+	}
+}
+
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// bindError request bind or validation error
+type bindError struct {
+	reply string
+	real  string
+}
+
+func newBindError(c *Context, err error) ErrorMsg {
+	var ves validator.ValidationErrors
+	if errors.As(err, &ves) {
+		var lang string
+		if langs := c.acceptLanguages(); len(langs) > 0 {
+			lang = langs[0]
+		}
+		trans := getTranslator(lang)
+		msgs := make([]string, 0, len(ves))
+		for _, fe := range ves {
+			msgs = append(msgs, fe.Translate(trans))
+		}
+		return &bindError{reply: strings.Join(msgs, "; "), real: err.Error()}
+	}
+	return ErrInvalidParams.Wrap(err)
+}
+
+// GetCode error code
+func (e *bindError) GetCode() string {
+	return ErrInvalidParams.Code
+}
+
+// GetReal real error message, only log
+func (e *bindError) GetReal() string {
+	return e.real
+}
+
+// GetReply user reply
+func (e *bindError) GetReply() string {
+	return e.reply
+}
+
+var (
+	validatorOnce sync.Once
+	// typedValidator validator of typed handlers, gin's binding.Validator is left untouched
+	typedValidator *validator.Validate
+	translators    = map[string]ut.Translator{}
+)
+
+// Validator validator of the requests of typed handlers, eg: register custom validations
+// before the routes are served. Rules are read from `binding` tags.
+func Validator() *validator.Validate {
+	validatorOnce.Do(initValidator)
+	return typedValidator
+}
+
+// validateRequest validate struct, or structs in slice like gin's default validator
+func validateRequest(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return Validator().Struct(v.Interface())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateRequest(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getTranslator return validator translator, support en and zh, default en
+func getTranslator(lang string) ut.Translator {
+	validatorOnce.Do(initValidator)
+	if strings.HasPrefix(lang, "zh") {
+		return translators["zh"]
+	}
+	return translators["en"]
+}
+
+func initValidator() {
+	v := validator.New()
+	v.SetTagName("binding")
+	// field name in messages use json/form/uri/header tag name
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri", "header"} {
+			name := strings.SplitN(fld.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return fld.Name
+	})
+	enLocale, zhLocale := en.New(), zh.New()
+	uni := ut.New(enLocale, enLocale, zhLocale)
+	enTrans, _ := uni.GetTranslator("en")
+	zhTrans, _ := uni.GetTranslator("zh")
+	if err := en_translations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		panic(fmt.Sprintf("register en validator translations failed, %v", err))
+	}
+	if err := zh_translations.RegisterDefaultTranslations(v, zhTrans); err != nil {
+		panic(fmt.Sprintf("register zh validator translations failed, %v", err))
+	}
+	translators["en"] = enTrans
+	translators["zh"] = zhTrans
+	typedValidator = v
+}
@@ -0,0 +1,115 @@
+package frame
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type bindUserReq struct {
+	ID    int    `uri:"id" binding:"required"`
+	Page  int    `form:"page"`
+	Token string `header:"X-Token"`
+	Name  string `json:"name"`
+}
+
+// newRouteContext context of a request matched to a route with path params
+func newRouteContext(r *http.Request, params gin.Params) (*Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	gtx, _ := gin.CreateTestContext(w)
+	gtx.Request = r
+	gtx.Params = params
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return &Context{Gtx: gtx, Entry: logrus.NewEntry(l), config: &Config{}}, w
+}
+
+func TestBindRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		header http.Header
+		body   string
+		want   bindUserReq
+	}{
+		{name: "tagged sources", target: "/users/5?page=2", header: http.Header{"X-Token": {"t"}}, body: `{"name":"a"}`, want: bindUserReq{ID: 5, Page: 2, Token: "t", Name: "a"}},
+		{name: "query can not override path", target: "/users/5?ID=999&id=999", want: bindUserReq{ID: 5}},
+		{name: "body can not override path", target: "/users/5", body: `{"ID":777}`, want: bindUserReq{ID: 5}},
+		{name: "untagged query field", target: "/users/5?Name=x&Token=x", want: bindUserReq{ID: 5}},
+		{name: "untagged header field", target: "/users/5", header: http.Header{"Name": {"x"}, "Id": {"9"}}, want: bindUserReq{ID: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			if tt.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			c, _ := newRouteContext(r, gin.Params{{Key: "id", Value: "5"}})
+			got := bindUserReq{}
+			if errMsg := c.bindRequest(&got); errMsg != nil {
+				t.Fatalf("bindRequest() error = %v", errMsg.GetReal())
+			}
+			if got != tt.want {
+				t.Errorf("bindRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleNilError(t *testing.T) {
+	notFound := registerTestCode(t, "T404", http.StatusNotFound, "not found")
+	tests := []struct {
+		name       string
+		fn         func(*Context, *struct{}) (*string, ErrorMsg)
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name: "nil",
+			fn: func(*Context, *struct{}) (*string, ErrorMsg) {
+				return nil, nil
+			},
+			wantStatus: http.StatusOK,
+			wantCode:   successCode,
+		},
+		{
+			name: "typed nil",
+			fn: func(*Context, *struct{}) (*string, ErrorMsg) {
+				var err *Error
+				return nil, err
+			},
+			wantStatus: http.StatusOK,
+			wantCode:   successCode,
+		},
+		{
+			name: "error",
+			fn: func(*Context, *struct{}) (*string, ErrorMsg) {
+				return nil, notFound.New()
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "T404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newRouteContext(httptest.NewRequest(http.MethodGet, "/", nil), nil)
+			Handle(tt.fn)(c)
+			resp := Response{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if w.Code != tt.wantStatus || resp.Code != tt.wantCode {
+				t.Errorf("Handle() = %v %v, want %v %v", w.Code, resp.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook app lifecycle hook
+type Hook func(ctx context.Context) error
+
+type lifecycle struct {
+	sync.Mutex
+	startHooks []Hook
+	stopHooks  []Hook
+	servers    []*http.Server
+	stopOnce   sync.Once
+	stopErr    error
+	// open websocket connections, hijacked connections are not drained by http.Server.Shutdown
+	wsConns  map[*WSConn]struct{}
+	wsWG     sync.WaitGroup
+	stopping bool
+}
+
+// OnStart register hooks, executed in order before servers start listening.
+// Run returns the first hook error without starting any server.
+func (e *App) OnStart(hooks ...Hook) {
+	e.lifecycle.Lock()
+	defer e.lifecycle.Unlock()
+	e.lifecycle.startHooks = append(e.lifecycle.startHooks, hooks...)
+}
+
+// OnStop register hooks, executed in reverse order after servers are drained
+// and before mysql/redis clients are closed.
+func (e *App) OnStop(hooks ...Hook) {
+	e.lifecycle.Lock()
+	defer e.lifecycle.Unlock()
+	e.lifecycle.stopHooks = append(e.lifecycle.stopHooks, hooks...)
+}
+
+func (e *App) runStartHooks(ctx context.Context) error {
+	e.lifecycle.Lock()
+	hooks := append([]Hook(nil), e.lifecycle.startHooks...)
+	e.lifecycle.Unlock()
+	for _, h := range hooks {
+		if err := h(ctx); err != nil {
+			return fmt.Errorf("start hook: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *App) runStopHooks(ctx context.Context) []error {
+	e.lifecycle.Lock()
+	hooks := append([]Hook(nil), e.lifecycle.stopHooks...)
+	e.lifecycle.Unlock()
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop hook: %w", err))
+		}
+	}
+	return errs
+}
+
+// serve start server in background, listen error is sent to errCh
+func (e *App) serve(name string, srv *http.Server, errCh chan<- error) {
+	e.lifecycle.Lock()
+	e.lifecycle.servers = append(e.lifecycle.servers, srv)
+	e.lifecycle.Unlock()
+	go func() {
+		logrus.Infof("%s server listen %s\n", name, srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("%s server: %w", name, err)
+		}
+	}()
+}
+
+// waitSignal block until SIGINT/SIGTERM or a server error
+func waitSignal(errCh <-chan error) error {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case sig := <-quit:
+		logrus.Infof("receive signal %s, shutting down\n", sig)
+		return nil
+	case err := <-errCh:
+		logrus.Errorf("server failed, shutting down: %v\n", err)
+		return err
+	}
+}
+
+// Shutdown graceful shutdown: drain servers and close websocket connections within http_server.shutdown_timeout_sec,
+// run stop hooks, then close mysql and redis clients and flush tracing spans. It is safe to call more than once.
+func (e *App) Shutdown() error {
+	e.lifecycle.stopOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), e.config.getShutdownTimeout())
+		defer cancel()
+
+		var errs []error
+		e.lifecycle.Lock()
+		servers := e.lifecycle.servers
+		e.lifecycle.Unlock()
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for _, srv := range servers {
+			wg.Add(1)
+			go func(srv *http.Server) {
+				defer wg.Done()
+				if err := srv.Shutdown(ctx); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("shutdown server %s: %w", srv.Addr, err))
+					mu.Unlock()
+				}
+			}(srv)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.grpcShutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+		wg.Wait()
+		e.lifecycle.closeWSConns(ctx)
+		// write queued access logs
+		if err := e.flushAccessLog(ctx); err != nil {
+			errs = append(errs, err)
+		}
+
+		errs = append(errs, e.runStopHooks(ctx)...)
+		if err := closeMySQLServers(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := closeRedisServers(); err != nil {
+			errs = append(errs, err)
+		}
+		// flush spans of stop hooks
+		if err := shutdownTracing(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		e.lifecycle.stopErr = errors.Join(errs...)
+		if e.lifecycle.stopErr != nil {
+			logrus.Errorf("shutdown finished with errors: %v\n", e.lifecycle.stopErr)
+		} else {
+			logrus.Infoln("shutdown finished")
+		}
+	})
+	return e.lifecycle.stopErr
+}
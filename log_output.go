@@ -0,0 +1,337 @@
+package frame
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// log categories, each category can be routed to its own outputs
+const (
+	LogCategoryApp    = "app"
+	LogCategoryAccess = "access" // access logs of http server, grpc, websocket and http client
+	LogCategorySQL    = "sql"
+	LogCategoryRedis  = "redis"
+)
+
+// log output types
+const (
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+	LogOutputFile   = "file"
+	LogOutputSyslog = "syslog"
+)
+
+// file time rotation
+const (
+	LogRotateHourly = "hourly"
+	LogRotateDaily  = "daily"
+)
+
+// LogConfig log outputs and the outputs of each category, categories without outputs write to app outputs
+type LogConfig struct {
+	Outputs     []LogOutputConfig `json:"outputs"`
+	App         []string          `json:"app"`    // output names, default stderr
+	Access      []string          `json:"access"` // default app outputs
+	SQL         []string          `json:"sql"`    // default app outputs
+	Redis       []string          `json:"redis"`  // default app outputs
+	Levels      map[string]string `json:"levels"` // level of loggers app/http/sql/redis/http_client, default log_level
+	Watch       bool              `json:"watch"`  // reload log_level and log.levels when the config file changes
+	Admin       LogAdminConfig    `json:"admin"`
+	Backend     string            `json:"backend"`                                                      // logrus/slog, default logrus
+	SlogDefault bool              `json:"slog_default" yaml:"slog_default" mapstructure:"slog_default"` // slog.Default() writes to the app logger, eg: logs of third party libraries
+}
+
+// LogOutputConfig log output
+type LogOutputConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // stdout/stderr/file/syslog
+	// file
+	Filename   string `json:"filename"`
+	MaxSizeMB  int    `json:"max_size_mb" yaml:"max_size_mb" mapstructure:"max_size_mb"`    // rotate when the file is larger, default 100
+	Rotate     string `json:"rotate"`                                                       // also rotate by time, hourly/daily, default size only
+	MaxAgeDays int    `json:"max_age_days" yaml:"max_age_days" mapstructure:"max_age_days"` // remove rotated files older than, default keep
+	MaxBackups int    `json:"max_backups" yaml:"max_backups" mapstructure:"max_backups"`    // rotated files kept, default keep all
+	Compress   bool   `json:"compress"`                                                     // gzip rotated files
+	LocalTime  bool   `json:"local_time" yaml:"local_time" mapstructure:"local_time"`       // local time in rotated file names and daily rotation, default UTC
+	// syslog, local socket
+	Tag      string `json:"tag"`      // default project
+	Facility string `json:"facility"` // local0 ~ local7, default local0
+}
+
+var syslogFacilities = []string{"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"}
+
+func (oc LogOutputConfig) getMaxSizeMB() int {
+	if oc.MaxSizeMB <= 0 {
+		return defaultLogFileMaxSizeMB
+	}
+	return oc.MaxSizeMB
+}
+
+// getFacility index of the facility in syslogFacilities
+func (oc LogOutputConfig) getFacility() int {
+	for i, f := range syslogFacilities {
+		if f == oc.Facility {
+			return i
+		}
+	}
+	return 0
+}
+
+// Validate check log config
+func (lc LogConfig) Validate() []error {
+	var errs []error
+	names := map[string]bool{}
+	for _, o := range lc.Outputs {
+		if o.Name == "" {
+			errs = append(errs, errors.New("please fill in the correct log.outputs name in the configuration file, it can't be empty"))
+		}
+		if names[o.Name] {
+			errs = append(errs, fmt.Errorf("log output name %s is duplicated, please reset it", o.Name))
+		}
+		names[o.Name] = true
+		switch o.Type {
+		case LogOutputStdout, LogOutputStderr, LogOutputSyslog:
+		case LogOutputFile:
+			if o.Filename == "" {
+				errs = append(errs, fmt.Errorf("please fill in the correct log.outputs %s filename in the configuration file", o.Name))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("please fill in the correct log.outputs %s type in the configuration file, choose one of: stdout/stderr/file/syslog", o.Name))
+		}
+		if !(o.Rotate == "" || o.Rotate == LogRotateHourly || o.Rotate == LogRotateDaily) {
+			errs = append(errs, fmt.Errorf("please fill in the correct log.outputs %s rotate in the configuration file, choose one of: hourly/daily", o.Name))
+		}
+		if o.Facility != "" && !slices.Contains(syslogFacilities, o.Facility) {
+			errs = append(errs, fmt.Errorf("please fill in the correct log.outputs %s facility in the configuration file, choose one of: local0 ~ local7", o.Name))
+		}
+	}
+	if !(lc.Backend == "" || lc.Backend == LogBackendLogrus || lc.Backend == LogBackendSlog) {
+		errs = append(errs, errors.New("please fill in the correct log.backend in the configuration file, choose one of: logrus/slog"))
+	}
+	for name, level := range lc.Levels {
+		if _, ok := loggerCategory[name]; !ok {
+			errs = append(errs, fmt.Errorf("log.levels logger %s is unknown, choose one of: app/http/sql/redis/http_client", name))
+		}
+		if _, ok := logm[strings.ToLower(level)]; !ok {
+			errs = append(errs, fmt.Errorf("please fill in the correct log.levels %s level in the configuration file, choose one of: trace/debug/info/warn/error", name))
+		}
+	}
+	for category, outputs := range map[string][]string{LogCategoryApp: lc.App, LogCategoryAccess: lc.Access, LogCategorySQL: lc.SQL, LogCategoryRedis: lc.Redis} {
+		for _, name := range outputs {
+			if !names[name] {
+				errs = append(errs, fmt.Errorf("log.%s output %s is not declared in log.outputs, please reset it", category, name))
+			}
+		}
+	}
+	return errs
+}
+
+// logOutputs writers and hooks of log categories, shared by all apps
+type logOutputs struct {
+	sync.RWMutex
+	categories map[string]io.Writer
+	hooks      map[string][]logrus.Hook // syslog outputs, written at the severity of the entry level
+	files      []*rotateFile
+}
+
+// outputHook hook of a log output, replaced when the outputs of a logger are set
+type outputHook interface {
+	logrus.Hook
+	outputHook()
+}
+
+var (
+	logOutputsOnce sync.Once
+	outputs        = &logOutputs{categories: map[string]io.Writer{}, hooks: map[string][]logrus.Hook{}}
+)
+
+// newLogOutputs open log outputs, rotated files are reopened on SIGHUP
+func newLogOutputs(conf *Config) {
+	logOutputsOnce.Do(func() {
+		lc := conf.Log
+		if len(lc.Outputs) == 0 {
+			return
+		}
+		writers := map[string]io.Writer{}
+		hooks := map[string]logrus.Hook{}
+		for _, o := range lc.Outputs {
+			w, hook, err := outputs.open(o, conf.Project)
+			if err != nil {
+				logrus.Fatalln(fmt.Errorf("open log output %s: %w", o.Name, err))
+			}
+			if hook != nil {
+				hooks[o.Name] = hook
+			} else {
+				writers[o.Name] = w
+			}
+		}
+		app, appHooks := multiWriter(writers, lc.App), outputHooks(hooks, lc.App)
+		if app == nil && len(appHooks) > 0 {
+			app = io.Discard
+		} else if app == nil {
+			app = os.Stderr
+		}
+		outputs.Lock()
+		outputs.categories[LogCategoryApp] = app
+		outputs.hooks[LogCategoryApp] = appHooks
+		for category, names := range map[string][]string{LogCategoryAccess: lc.Access, LogCategorySQL: lc.SQL, LogCategoryRedis: lc.Redis} {
+			if len(names) == 0 {
+				outputs.categories[category] = app
+				outputs.hooks[category] = appHooks
+				continue
+			}
+			w, hs := multiWriter(writers, names), outputHooks(hooks, names)
+			if w == nil {
+				w = io.Discard
+			}
+			outputs.categories[category] = w
+			outputs.hooks[category] = hs
+		}
+		outputs.Unlock()
+		setLogOutput(logrus.StandardLogger(), app, appHooks)
+		if len(outputs.files) > 0 {
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			go outputs.reopenOnSignal(hup)
+		}
+	})
+}
+
+// open writer of the output, syslog outputs are hooks
+func (lo *logOutputs) open(conf LogOutputConfig, project string) (io.Writer, logrus.Hook, error) {
+	switch conf.Type {
+	case LogOutputStdout:
+		return os.Stdout, nil, nil
+	case LogOutputFile:
+		f := &rotateFile{
+			Logger: &lumberjack.Logger{
+				Filename:   conf.Filename,
+				MaxSize:    conf.getMaxSizeMB(),
+				MaxAge:     conf.MaxAgeDays,
+				MaxBackups: conf.MaxBackups,
+				Compress:   conf.Compress,
+				LocalTime:  conf.LocalTime,
+			},
+			rotate: conf.Rotate,
+		}
+		if f.rotate != "" {
+			go f.rotateLoop()
+		}
+		lo.files = append(lo.files, f)
+		return f, nil, nil
+	case LogOutputSyslog:
+		tag := conf.Tag
+		if tag == "" {
+			tag = project
+		}
+		hook, err := newSyslogHook(conf.getFacility(), tag)
+		return nil, hook, err
+	}
+	return os.Stderr, nil, nil
+}
+
+// reopenOnSignal close log files on SIGHUP, they are reopened by the next write, eg: after logrotate moved them
+func (lo *logOutputs) reopenOnSignal(hup <-chan os.Signal) {
+	for range hup {
+		for _, f := range lo.files {
+			if err := f.Close(); err != nil {
+				logrus.Errorf("reopen log file %s: %v\n", f.Filename, err)
+			}
+		}
+		logrus.Infoln("log files reopened")
+	}
+}
+
+// logOutput writer and hooks of the category, false when log outputs are not configured
+func logOutput(category string) (io.Writer, []logrus.Hook, bool) {
+	outputs.RLock()
+	defer outputs.RUnlock()
+	w, ok := outputs.categories[category]
+	return w, outputs.hooks[category], ok
+}
+
+// setLogOutput set the writer and output hooks of l, other hooks are kept
+func setLogOutput(l *logrus.Logger, w io.Writer, hooks []logrus.Hook) {
+	levelHooks := logrus.LevelHooks{}
+	for level, hs := range l.Hooks {
+		for _, h := range hs {
+			if _, ok := h.(outputHook); !ok {
+				levelHooks[level] = append(levelHooks[level], h)
+			}
+		}
+	}
+	for _, h := range hooks {
+		levelHooks.Add(h)
+	}
+	l.SetOutput(w)
+	l.ReplaceHooks(levelHooks)
+}
+
+// accessEntry entry of the http logger with the fields of entry
+func accessEntry(entry *logrus.Entry) *logrus.Entry {
+	traceID, _ := entry.Data[TraceIDKey].(string)
+	return traceLogger(LoggerHTTP, traceID).WithFields(entry.Data)
+}
+
+func outputHooks(hooks map[string]logrus.Hook, names []string) []logrus.Hook {
+	var hs []logrus.Hook
+	for _, name := range names {
+		if h, ok := hooks[name]; ok {
+			hs = append(hs, h)
+		}
+	}
+	return hs
+}
+
+func multiWriter(writers map[string]io.Writer, names []string) io.Writer {
+	var ws []io.Writer
+	for _, name := range names {
+		if w, ok := writers[name]; ok {
+			ws = append(ws, w)
+		}
+	}
+	switch len(ws) {
+	case 0:
+		return nil
+	case 1:
+		return ws[0]
+	}
+	return io.MultiWriter(ws...)
+}
+
+// rotateFile size rotated file, also rotated hourly/daily when rotate is set
+type rotateFile struct {
+	*lumberjack.Logger
+	rotate string
+}
+
+func (f *rotateFile) rotateLoop() {
+	for {
+		time.Sleep(time.Until(nextRotateTime(time.Now(), f.rotate, f.LocalTime)))
+		if err := f.Rotate(); err != nil {
+			logrus.Errorf("rotate log file %s: %v\n", f.Filename, err)
+		}
+	}
+}
+
+// nextRotateTime start of the next hour or day
+func nextRotateTime(now time.Time, rotate string, local bool) time.Time {
+	if !local {
+		now = now.UTC()
+	}
+	if rotate == LogRotateHourly {
+		return time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, now.Location())
+	}
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
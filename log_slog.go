@@ -0,0 +1,266 @@
+package frame
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"runtime"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// log backends, the backend formats the entries of frame loggers
+const (
+	LogBackendLogrus = "logrus"
+	LogBackendSlog   = "slog" // entries are formatted by slog.JSONHandler or slog.TextHandler of log_mode
+)
+
+// slog levels of logrus trace, fatal and panic levels
+const (
+	slogLevelTrace = slog.LevelDebug - 4
+	slogLevelFatal = slog.LevelError + 4
+	slogLevelPanic = slog.LevelError + 8
+)
+
+// slogPCKey entry field carrying the caller pc of a slog record, removed before the entry is formatted
+const slogPCKey = "_slog_pc"
+
+func logrus2slogLevel(l logrus.Level) slog.Level {
+	switch l {
+	case logrus.TraceLevel:
+		return slogLevelTrace
+	case logrus.DebugLevel:
+		return slog.LevelDebug
+	case logrus.InfoLevel:
+		return slog.LevelInfo
+	case logrus.WarnLevel:
+		return slog.LevelWarn
+	case logrus.ErrorLevel:
+		return slog.LevelError
+	case logrus.FatalLevel:
+		return slogLevelFatal
+	}
+	return slogLevelPanic
+}
+
+// slog2logrusLevel levels above error are written as error, a slog record never exits or panics
+func slog2logrusLevel(l slog.Level) logrus.Level {
+	switch {
+	case l < slog.LevelDebug:
+		return logrus.TraceLevel
+	case l < slog.LevelInfo:
+		return logrus.DebugLevel
+	case l < slog.LevelWarn:
+		return logrus.InfoLevel
+	case l < slog.LevelError:
+		return logrus.WarnLevel
+	}
+	return logrus.ErrorLevel
+}
+
+// slogFormatter format entries with a slog handler of log_mode, a custom handler writes entries itself
+type slogFormatter struct {
+	buf     bytes.Buffer
+	handler slog.Handler
+	custom  bool
+}
+
+func newSlogFormatter(mode string) *slogFormatter {
+	f := &slogFormatter{}
+	// levels are checked by logrus
+	opts := &slog.HandlerOptions{Level: slogLevelTrace}
+	if mode == ModelText {
+		opts.ReplaceAttr = replaceSlogTextAttr
+		f.handler = slog.NewTextHandler(&f.buf, opts)
+	} else {
+		opts.ReplaceAttr = replaceSlogAttr
+		f.handler = slog.NewJSONHandler(&f.buf, opts)
+	}
+	return f
+}
+
+// replaceSlogAttr names of logrus levels
+func replaceSlogAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		switch a.Value.Any() {
+		case slogLevelTrace:
+			a.Value = slog.StringValue("TRACE")
+		case slogLevelFatal:
+			a.Value = slog.StringValue("FATAL")
+		case slogLevelPanic:
+			a.Value = slog.StringValue("PANIC")
+		}
+	}
+	return a
+}
+
+// replaceSlogTextAttr source as file:line like slog.TextHandler
+func replaceSlogTextAttr(groups []string, a slog.Attr) slog.Attr {
+	if src, ok := a.Value.Any().(*slog.Source); ok && len(groups) == 0 && a.Key == slog.SourceKey {
+		return slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", src.File, src.Line))
+	}
+	return replaceSlogAttr(groups, a)
+}
+
+// Format implement logrus.Formatter, called under the logger lock so buf is not shared
+func (f *slogFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	ctx := e.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	level := logrus2slogLevel(e.Level)
+	if f.custom && !f.handler.Enabled(ctx, level) {
+		return nil, nil
+	}
+	// the caller is added as the source attr, the pc of a frame is not resolved to the same frame when it is inlined
+	r := slog.NewRecord(e.Time, level, e.Message, 0)
+	if e.HasCaller() {
+		r.AddAttrs(slog.Any(slog.SourceKey, &slog.Source{Function: e.Caller.Function, File: path.Base(e.Caller.File), Line: e.Caller.Line}))
+	}
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.AddAttrs(slog.Any(k, e.Data[k]))
+	}
+	if f.custom {
+		return nil, f.handler.Handle(ctx, r)
+	}
+	f.buf.Reset()
+	if err := f.handler.Handle(ctx, r); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), f.buf.Bytes()...), nil
+}
+
+// slogCaller frame of the pc of a slog record, nil when the record has no pc, eg: the log package without Lshortfile
+func slogCaller(pc uintptr) *runtime.Frame {
+	if pc == 0 {
+		return nil
+	}
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return &f
+}
+
+// slogHandler slog.Handler writing records to a frame logger, so they share its level, outputs and format.
+// The trace id of the record context is added when the record has none.
+type slogHandler struct {
+	entry  *logrus.Entry
+	name   string // logger name of NewSlogHandler, the logger of the trace id of the record is used
+	fields logrus.Fields
+	prefix string // groups joined by "."
+}
+
+// NewSlogHandler slog.Handler writing to the named logger, eg: slog.New(frame.NewSlogHandler(frame.LoggerApp)).
+// Records logged with a frame Context or a context of WithTraceContext carry the trace id.
+func NewSlogHandler(name string) slog.Handler {
+	h := newSlogHandler(logrus.NewEntry(NamedLogger(name)))
+	h.name = name
+	return h
+}
+
+func newSlogHandler(entry *logrus.Entry) *slogHandler {
+	fields := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		fields[k] = v
+	}
+	return &slogHandler{entry: entry, fields: fields}
+}
+
+// Enabled implement slog.Handler
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger(ctx).IsLevelEnabled(slog2logrusLevel(level))
+}
+
+// logger logger of the trace id of ctx for handlers of NewSlogHandler
+func (h *slogHandler) logger(ctx context.Context) *logrus.Logger {
+	if h.name == "" {
+		return h.entry.Logger
+	}
+	return traceLogger(h.name, getTraceIDFromContext(ctx))
+}
+
+// Handle implement slog.Handler
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(logrus.Fields, len(h.fields)+r.NumAttrs()+2)
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(fields, h.prefix, a)
+		return true
+	})
+	if _, ok := fields[TraceIDKey]; !ok {
+		if traceID := getTraceIDFromContext(ctx); traceID != "" {
+			fields[TraceIDKey] = traceID
+		}
+	}
+	fields[slogPCKey] = r.PC
+	entry := h.entry.WithFields(fields).WithTime(r.Time)
+	if h.name != "" {
+		traceID, _ := fields[TraceIDKey].(string)
+		entry.Logger = traceLogger(h.name, traceID)
+	}
+	if ctx != nil {
+		entry = entry.WithContext(ctx)
+	}
+	entry.Log(slog2logrusLevel(r.Level), r.Message)
+	return nil
+}
+
+// WithAttrs implement slog.Handler
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := &slogHandler{entry: h.entry, name: h.name, fields: make(logrus.Fields, len(h.fields)+len(attrs)), prefix: h.prefix}
+	for k, v := range h.fields {
+		n.fields[k] = v
+	}
+	for _, a := range attrs {
+		addSlogAttr(n.fields, h.prefix, a)
+	}
+	return n
+}
+
+// WithGroup implement slog.Handler, keys of the group are prefixed with "name."
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{entry: h.entry, name: h.name, fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// addSlogAttr add attr to fields, groups are flattened to dotted keys
+func addSlogAttr(fields logrus.Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(fields, prefix, ga)
+		}
+		return
+	}
+	fields[prefix+a.Key] = a.Value.Any()
+}
+
+// SetSlogHandler write the entries of all frame loggers with handler instead of frame outputs,
+// eg: a handler exporting logs to OpenTelemetry. nil restores log.backend.
+// The handler must not write to a handler of NewSlogHandler.
+func SetSlogHandler(handler slog.Handler) {
+	loggers.Lock()
+	loggers.handler = handler
+	loggers.Unlock()
+	loggers.setFormatters()
+}
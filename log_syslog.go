@@ -0,0 +1,57 @@
+//go:build !windows && !plan9
+
+package frame
+
+import (
+	"log/syslog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// syslogWriter severity methods of *syslog.Writer
+type syslogWriter interface {
+	Crit(m string) error
+	Err(m string) error
+	Warning(m string) error
+	Info(m string) error
+	Debug(m string) error
+}
+
+// syslogHook write entries to the local syslog socket at the severity of their level
+type syslogHook struct {
+	w syslogWriter
+}
+
+// newSyslogHook hook of the local syslog socket, facility is the index of local0 ~ local7
+func newSyslogHook(facility int, tag string) (logrus.Hook, error) {
+	w, err := syslog.New(syslog.LOG_LOCAL0+syslog.Priority(facility<<3)|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogHook{w: w}, nil
+}
+
+func (h *syslogHook) outputHook() {}
+
+func (h *syslogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *syslogHook) Fire(entry *logrus.Entry) error {
+	b, err := entry.Bytes()
+	if err != nil {
+		return err
+	}
+	msg := string(b)
+	switch entry.Level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return h.w.Crit(msg)
+	case logrus.ErrorLevel:
+		return h.w.Err(msg)
+	case logrus.WarnLevel:
+		return h.w.Warning(msg)
+	case logrus.InfoLevel:
+		return h.w.Info(msg)
+	}
+	return h.w.Debug(msg)
+}
@@ -0,0 +1,84 @@
+//go:build !windows && !plan9
+
+package frame
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordSyslog syslog writer recording the severities written
+type recordSyslog struct {
+	severities []string
+}
+
+func (r *recordSyslog) Crit(string) error    { return r.add("crit") }
+func (r *recordSyslog) Err(string) error     { return r.add("err") }
+func (r *recordSyslog) Warning(string) error { return r.add("warning") }
+func (r *recordSyslog) Info(string) error    { return r.add("info") }
+func (r *recordSyslog) Debug(string) error   { return r.add("debug") }
+
+func (r *recordSyslog) add(severity string) error {
+	r.severities = append(r.severities, severity)
+	return nil
+}
+
+// recordHook hook counting the entries fired
+type recordHook struct {
+	n int
+}
+
+func (h *recordHook) Levels() []logrus.Level   { return logrus.AllLevels }
+func (h *recordHook) Fire(*logrus.Entry) error { h.n++; return nil }
+
+func TestSyslogHookSeverity(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *logrus.Logger)
+		want string
+	}{
+		{name: "error", log: func(l *logrus.Logger) { l.Error("e") }, want: "err"},
+		{name: "warn", log: func(l *logrus.Logger) { l.Warn("w") }, want: "warning"},
+		{name: "info", log: func(l *logrus.Logger) { l.Info("i") }, want: "info"},
+		{name: "debug", log: func(l *logrus.Logger) { l.Debug("d") }, want: "debug"},
+		{name: "trace", log: func(l *logrus.Logger) { l.Trace("t") }, want: "debug"},
+		{name: "panic", log: func(l *logrus.Logger) { defer func() { recover() }(); l.Panic("p") }, want: "crit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &recordSyslog{}
+			l := logrus.New()
+			l.SetLevel(logrus.TraceLevel)
+			setLogOutput(l, io.Discard, []logrus.Hook{&syslogHook{w: w}})
+			tt.log(l)
+			if !reflect.DeepEqual(w.severities, []string{tt.want}) {
+				t.Errorf("syslog severities = %v, want [%v]", w.severities, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLogOutput(t *testing.T) {
+	l := logrus.New()
+	user := &recordHook{}
+	l.AddHook(user)
+	first, second := &recordSyslog{}, &recordSyslog{}
+	setLogOutput(l, io.Discard, []logrus.Hook{&syslogHook{w: first}})
+	var buf bytes.Buffer
+	// output hooks are replaced, hooks of users are kept
+	setLogOutput(l, &buf, []logrus.Hook{&syslogHook{w: second}})
+	l.Info("hi")
+	if len(first.severities) != 0 || len(second.severities) != 1 {
+		t.Errorf("syslog entries = %d, %d, want 0, 1", len(first.severities), len(second.severities))
+	}
+	if user.n != 1 {
+		t.Errorf("user hook fired %d times, want 1", user.n)
+	}
+	if buf.Len() == 0 {
+		t.Errorf("writer got nothing")
+	}
+}
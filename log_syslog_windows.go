@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package frame
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newSyslogHook syslog is not supported
+func newSyslogHook(facility int, tag string) (logrus.Hook, error) {
+	return nil, errors.New("syslog output is not supported on this platform")
+}
@@ -0,0 +1,435 @@
+package frame
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// shared loggers, each one has its own level, eg: frame.SetLogLevel(frame.LoggerSQL, "debug", 10*time.Minute)
+const (
+	LoggerApp        = "app"
+	LoggerHTTP       = "http" // access logs of http server, grpc and websocket
+	LoggerSQL        = "sql"
+	LoggerRedis      = "redis"
+	LoggerHTTPClient = "http_client"
+)
+
+var loggerNames = []string{LoggerApp, LoggerHTTP, LoggerSQL, LoggerRedis, LoggerHTTPClient}
+
+// loggerCategory output category of loggers
+var loggerCategory = map[string]string{
+	LoggerApp:        LogCategoryApp,
+	LoggerHTTP:       LogCategoryAccess,
+	LoggerSQL:        LogCategorySQL,
+	LoggerRedis:      LogCategoryRedis,
+	LoggerHTTPClient: LogCategoryAccess,
+}
+
+// LogAdminConfig log level admin endpoint config
+type LogAdminConfig struct {
+	Enable bool   `json:"enable"`
+	Server string `json:"server"` // serve on which http server, default metrics server
+	Path   string `json:"path"`   // default /admin/log/level
+	Token  string `json:"token"`  // required by PUT/POST as "Authorization: Bearer <token>"
+}
+
+func (ac LogAdminConfig) getServer() string {
+	if ac.Server == "" {
+		return defaultMetricsName
+	}
+	return ac.Server
+}
+
+func (ac LogAdminConfig) getPath() string {
+	if ac.Path == "" {
+		return defaultLogAdminPath
+	}
+	return ac.Path
+}
+
+// traceLevel log level of one trace id
+type traceLevel struct {
+	Level  string    `json:"level"`
+	Expire time.Time `json:"expire"`
+	level  logrus.Level
+}
+
+// loggerHierarchy loggers shared by the app, sql, redis and http client logs
+type loggerHierarchy struct {
+	sync.RWMutex
+	loggers    map[string]*logrus.Logger
+	configured map[string]logrus.Level // levels of the config file
+	levels     map[string]logrus.Level // configured or changed at runtime
+	reverts    map[string]*time.Timer
+	traces     map[string]traceLevel
+	// traceLoggers loggers at the level of traces more verbose than the shared loggers
+	traceLoggers map[traceLoggerKey]*logrus.Logger
+	mode         string       // log_mode
+	backend      string       // log.backend
+	handler      slog.Handler // set by SetSlogHandler
+}
+
+type traceLoggerKey struct {
+	name    string
+	traceID string
+}
+
+var loggers = newLoggerHierarchy()
+
+func newLoggerHierarchy() *loggerHierarchy {
+	h := &loggerHierarchy{
+		loggers:    map[string]*logrus.Logger{},
+		configured: map[string]logrus.Level{},
+		levels:     map[string]logrus.Level{},
+		reverts:    map[string]*time.Timer{},
+		traces:     map[string]traceLevel{},
+	}
+	for _, name := range loggerNames {
+		l := logrus.New()
+		l.SetReportCaller(true)
+		l.SetOutput(os.Stderr)
+		l.SetFormatter(&callerFormatter{defaultJSONLogFormatter})
+		h.loggers[name] = l
+		h.configured[name] = logrus.InfoLevel
+		h.levels[name] = logrus.InfoLevel
+	}
+	h.update()
+	return h
+}
+
+// NamedLogger shared logger by name, the app logger is returned for unknown names
+func NamedLogger(name string) *logrus.Logger {
+	if l, ok := loggers.loggers[name]; ok {
+		return l
+	}
+	return loggers.loggers[LoggerApp]
+}
+
+// traceLogger named logger of the entries of traceID, at the level of SetTraceLogLevel when it is more verbose
+func traceLogger(name, traceID string) *logrus.Logger {
+	if traceID != "" {
+		h := loggers
+		h.RLock()
+		l, ok := h.traceLoggers[traceLoggerKey{name: name, traceID: traceID}]
+		h.RUnlock()
+		if ok {
+			return l
+		}
+	}
+	return NamedLogger(name)
+}
+
+// traceEntry entry of traceLogger carrying the trace id
+func traceEntry(name, traceID string) *logrus.Entry {
+	return traceLogger(name, traceID).WithField(TraceIDKey, traceID)
+}
+
+// applyLogConfig set mode, outputs and levels of config, levels changed by SetLogLevel with a duration are kept until they revert
+func applyLogConfig(conf *Config) {
+	h := loggers
+	h.Lock()
+	h.mode = conf.LogMode
+	h.backend = conf.Log.Backend
+	for _, name := range loggerNames {
+		level := logrus.DebugLevel
+		if conf.LogLevel != "" {
+			level = log2Level(conf.LogLevel)
+		}
+		if v, ok := conf.Log.Levels[name]; ok {
+			level = log2Level(v)
+		}
+		h.configured[name] = level
+		if _, ok := h.reverts[name]; !ok {
+			h.levels[name] = level
+		}
+	}
+	h.update()
+	h.Unlock()
+	h.setFormatters()
+	if conf.Log.SlogDefault {
+		slog.SetDefault(slog.New(NewSlogHandler(LoggerApp)))
+	}
+}
+
+// setFormatters set formatters and outputs of loggers by mode and backend,
+// logrus formats entries under the logger lock, which is not taken with h locked
+func (h *loggerHierarchy) setFormatters() {
+	h.RLock()
+	mode, backend, handler := h.mode, h.backend, h.handler
+	h.RUnlock()
+	for _, name := range loggerNames {
+		setLogFormatter(h.loggers[name], name, mode, backend, handler)
+	}
+	// trace loggers are created with the new formatters
+	h.Lock()
+	h.update()
+	h.Unlock()
+	// frame messages logged by the standard logger follow the backend
+	if backend == LogBackendSlog || handler != nil {
+		logrus.SetFormatter(newLogFormatter(mode, backend, handler))
+	} else if _, ok := logrus.StandardLogger().Formatter.(*slogFormatter); ok {
+		logrus.SetFormatter(defaultJSONLogFormatter)
+	}
+}
+
+// setLogFormatter set the formatter and output of the named logger, a custom handler writes entries itself
+func setLogFormatter(l *logrus.Logger, name, mode, backend string, handler slog.Handler) {
+	w, hooks, ok := logOutput(loggerCategory[name])
+	if !ok {
+		w = os.Stderr
+	}
+	if handler != nil {
+		w, hooks = io.Discard, nil
+	}
+	l.SetFormatter(&callerFormatter{newLogFormatter(mode, backend, handler)})
+	setLogOutput(l, w, hooks)
+}
+
+// newLogFormatter formatter of log_mode and log.backend, handler of SetSlogHandler comes first
+func newLogFormatter(mode, backend string, handler slog.Handler) logrus.Formatter {
+	switch {
+	case handler != nil:
+		return &slogFormatter{handler: handler, custom: true}
+	case backend == LogBackendSlog:
+		return newSlogFormatter(mode)
+	case mode == "" || mode == ModeJSON:
+		return defaultJSONLogFormatter
+	}
+	return &logrus.TextFormatter{}
+}
+
+// update set logger levels and create loggers of traces more verbose than them,
+// so entries of other traces are dropped by the level check of logrus before they are formatted
+func (h *loggerHierarchy) update() {
+	for name, l := range h.loggers {
+		l.SetLevel(h.levels[name])
+	}
+	now := time.Now()
+	traceLoggers := map[traceLoggerKey]*logrus.Logger{}
+	for traceID, t := range h.traces {
+		if !t.Expire.After(now) {
+			continue
+		}
+		for _, name := range loggerNames {
+			if t.level <= h.levels[name] {
+				continue
+			}
+			l := logrus.New()
+			l.SetReportCaller(true)
+			l.SetLevel(t.level)
+			setLogFormatter(l, name, h.mode, h.backend, h.handler)
+			traceLoggers[traceLoggerKey{name: name, traceID: traceID}] = l
+		}
+	}
+	h.traceLoggers = traceLoggers
+}
+
+// SetLogLevel change the level of a logger at runtime, empty name changes all loggers.
+// The configured level is restored after d when d > 0.
+func SetLogLevel(name, level string, d time.Duration) error {
+	lv, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	names := loggerNames
+	if name != "" {
+		if _, ok := loggers.loggers[name]; !ok {
+			return fmt.Errorf("unknown logger %s", name)
+		}
+		names = []string{name}
+	}
+	h := loggers
+	h.Lock()
+	defer h.Unlock()
+	for _, name := range names {
+		h.levels[name] = lv
+		if t, ok := h.reverts[name]; ok {
+			t.Stop()
+			delete(h.reverts, name)
+		}
+		if d > 0 {
+			h.revertAfter(name, d)
+		}
+	}
+	h.update()
+	return nil
+}
+
+func (h *loggerHierarchy) revertAfter(name string, d time.Duration) {
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		h.Lock()
+		defer h.Unlock()
+		if h.reverts[name] != t {
+			return
+		}
+		delete(h.reverts, name)
+		h.levels[name] = h.configured[name]
+		h.update()
+	})
+	h.reverts[name] = t
+}
+
+// SetTraceLogLevel write logs of one trace id at level for d, default 10 minutes and at most 1 hour,
+// eg: debug one request in production. At most 100 trace ids have a level at the same time.
+func SetTraceLogLevel(traceID, level string, d time.Duration) error {
+	lv, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	if traceID == "" {
+		return fmt.Errorf("trace id can't be empty")
+	}
+	if d <= 0 {
+		d = defaultTraceLogLevelTTL
+	}
+	if d > maxTraceLogLevelTTL {
+		return fmt.Errorf("trace log level duration can't be longer than %s", maxTraceLogLevelTTL)
+	}
+	h := loggers
+	h.Lock()
+	defer h.Unlock()
+	if _, ok := h.traces[traceID]; !ok && len(h.traces) >= maxTraceLogLevels {
+		return fmt.Errorf("too many trace log levels, at most %d", maxTraceLogLevels)
+	}
+	expire := time.Now().Add(d)
+	h.traces[traceID] = traceLevel{Level: lv.String(), Expire: expire, level: lv}
+	h.update()
+	time.AfterFunc(d, func() {
+		h.Lock()
+		defer h.Unlock()
+		if t, ok := h.traces[traceID]; ok && t.Expire.Equal(expire) {
+			delete(h.traces, traceID)
+			h.update()
+		}
+	})
+	return nil
+}
+
+// LogLevels current level of loggers
+func LogLevels() map[string]string {
+	h := loggers
+	h.RLock()
+	defer h.RUnlock()
+	levels := map[string]string{}
+	for name, lv := range h.levels {
+		levels[name] = lv.String()
+	}
+	return levels
+}
+
+func traceLogLevels() map[string]traceLevel {
+	h := loggers
+	h.RLock()
+	defer h.RUnlock()
+	traces := map[string]traceLevel{}
+	for id, t := range h.traces {
+		traces[id] = t
+	}
+	return traces
+}
+
+// callerFormatter records of NewSlogHandler report their own caller
+type callerFormatter struct {
+	logrus.Formatter
+}
+
+func (f *callerFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	if pc, ok := e.Data[slogPCKey].(uintptr); ok {
+		delete(e.Data, slogPCKey)
+		e.Caller = slogCaller(pc)
+	}
+	return f.Formatter.Format(e)
+}
+
+// watchLogConfig reload log_level and log.levels when the config file changes
+func watchLogConfig(cm *ConfigManager) {
+	cm.OnChange(func(in fsnotify.Event) {
+		c := &Config{}
+		if err := cm.ReadConfigObject(c); err != nil {
+			logrus.Errorf("reload log config: %v\n", err)
+			return
+		}
+		if errs := c.Log.Validate(); len(errs) > 0 {
+			logrus.Errorf("reload log config: %v\n", errs)
+			return
+		}
+		applyLogConfig(c)
+		logrus.Infof("log levels reloaded: %v\n", LogLevels())
+	})
+}
+
+// logLevelRequest body of the admin endpoint
+type logLevelRequest struct {
+	Logger      string `json:"logger"`       // empty for all loggers
+	Level       string `json:"level"`        // trace/debug/info/warn/error
+	TraceID     string `json:"trace_id"`     // only change the level of this trace id
+	DurationSec int    `json:"duration_sec"` // revert after, default no revert, trace id default 600
+}
+
+// adminAuthorized token is required by Config.validate, nothing is allowed without it
+func adminAuthorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// mountLogAdmin GET current levels, PUT/POST change levels
+func (e *App) mountLogAdmin(mux *http.ServeMux, server *Server) {
+	token := e.config.Log.Admin.Token
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			if !adminAuthorized(r, token) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid admin token"})
+				return
+			}
+			var req logLevelRequest
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err == nil {
+				d := time.Duration(req.DurationSec) * time.Second
+				if req.TraceID != "" {
+					err = SetTraceLogLevel(req.TraceID, req.Level, d)
+				} else {
+					err = SetLogLevel(req.Logger, req.Level, d)
+				}
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			logrus.Infof("log level changed: %+v\n", req)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"levels": LogLevels(),
+			"traces": traceLogLevels(),
+		})
+	}
+	path := e.config.Log.Admin.getPath()
+	if mux != nil {
+		mux.HandleFunc(path, handler)
+		return
+	}
+	server.Engine.Any(path, gin.WrapF(handler))
+}
@@ -0,0 +1,102 @@
+package frame
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cleanTraceLogLevels remove the trace log levels set by the test
+func cleanTraceLogLevels(t *testing.T) {
+	t.Cleanup(func() {
+		loggers.Lock()
+		defer loggers.Unlock()
+		loggers.traces = map[string]traceLevel{}
+		loggers.update()
+	})
+}
+
+func TestAdminAuthorized(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   bool
+	}{
+		{name: "no token configured", token: "", header: "", want: false},
+		{name: "no token configured with header", token: "", header: "Bearer ", want: false},
+		{name: "missing header", token: "s3cret", header: "", want: false},
+		{name: "wrong token", token: "s3cret", header: "Bearer nope", want: false},
+		{name: "right token", token: "s3cret", header: "Bearer s3cret", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/admin/log/level", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if got := adminAuthorized(r, tt.token); got != tt.want {
+				t.Errorf("adminAuthorized() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogAdminValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		admin   LogAdminConfig
+		wantErr bool
+	}{
+		{name: "disabled", admin: LogAdminConfig{}},
+		{name: "metrics without token", admin: LogAdminConfig{Enable: true}, wantErr: true},
+		{name: "metrics with token", admin: LogAdminConfig{Enable: true, Token: "s3cret"}},
+		{name: "unknown server", admin: LogAdminConfig{Enable: true, Server: "api", Token: "s3cret"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			c.Log.Admin = tt.admin
+			gotErr := false
+			for _, err := range c.validate() {
+				gotErr = gotErr || strings.Contains(err.Error(), "log admin") || strings.Contains(err.Error(), "log.admin")
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("validate() log admin error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetTraceLogLevel(t *testing.T) {
+	cleanTraceLogLevels(t)
+	for i := 0; i < maxTraceLogLevels; i++ {
+		if err := SetTraceLogLevel(fmt.Sprintf("trace-%d", i), "debug", time.Minute); err != nil {
+			t.Fatalf("SetTraceLogLevel() error = %v", err)
+		}
+	}
+	tests := []struct {
+		name    string
+		traceID string
+		d       time.Duration
+		wantErr bool
+	}{
+		{name: "over the limit", traceID: "trace-new", d: time.Minute, wantErr: true},
+		{name: "reset an existing trace", traceID: "trace-0", d: time.Minute},
+		{name: "default duration", traceID: "trace-1", d: 0},
+		{name: "too long", traceID: "trace-2", d: maxTraceLogLevelTTL + time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetTraceLogLevel(tt.traceID, "debug", tt.d); (err != nil) != tt.wantErr {
+				t.Errorf("SetTraceLogLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if got := len(traceLogLevels()); got != maxTraceLogLevels {
+		t.Errorf("traceLogLevels() = %d traces, want %d", got, maxTraceLogLevels)
+	}
+}
@@ -0,0 +1,175 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// access log queue full policies
+const (
+	LogQueueDrop   = "drop"
+	LogQueueBlock  = "block"
+	LogQueueSample = "sample"
+)
+
+// LogQueueConfig bounded async access log queue, access logs are written by a fixed number of workers
+type LogQueueConfig struct {
+	Size        int     `json:"size"`                                                         // default 4096
+	Workers     int     `json:"workers"`                                                      // default 2
+	FullPolicy  string  `json:"full_policy" yaml:"full_policy" mapstructure:"full_policy"`    // drop/block/sample, default drop
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio" mapstructure:"sample_ratio"` // sample policy, ratio of entries kept once the queue is half full, default 0.1
+}
+
+func (lc LogQueueConfig) getSize() int {
+	if lc.Size <= 0 {
+		return defaultLogQueueSize
+	}
+	return lc.Size
+}
+
+func (lc LogQueueConfig) getWorkers() int {
+	if lc.Workers <= 0 {
+		return defaultLogQueueWorkers
+	}
+	return lc.Workers
+}
+
+func (lc LogQueueConfig) getFullPolicy() string {
+	if lc.FullPolicy == "" {
+		return LogQueueDrop
+	}
+	return lc.FullPolicy
+}
+
+func (lc LogQueueConfig) getSampleRatio() float64 {
+	if lc.SampleRatio <= 0 {
+		return defaultLogQueueSampleRatio
+	}
+	return lc.SampleRatio
+}
+
+// Validate check access log queue config
+func (lc LogQueueConfig) Validate() []error {
+	var errs []error
+	switch lc.FullPolicy {
+	case "", LogQueueDrop, LogQueueBlock, LogQueueSample:
+	default:
+		errs = append(errs, errors.New("please fill in the correct access_log.queue.full_policy in the configuration file, choose one of: drop/block/sample"))
+	}
+	if lc.SampleRatio < 0 || lc.SampleRatio > 1 {
+		errs = append(errs, errors.New("please fill in the correct access_log.queue.sample_ratio in the configuration file, range 0 ~ 1"))
+	}
+	return errs
+}
+
+// logQueue bounded queue of log tasks
+type logQueue struct {
+	mu      sync.RWMutex
+	conf    LogQueueConfig
+	metrics *metrics // nil when metrics are disabled
+	tasks   chan func()
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// newAccessLogQueue start access log workers of one app, dropped entries are counted by its metrics
+func newAccessLogQueue(conf *Config, m *metrics) *logQueue {
+	if !conf.EnableMetric {
+		m = nil
+	}
+	return newLogQueue(conf.AccessLog.Queue, m)
+}
+
+func newLogQueue(conf LogQueueConfig, m *metrics) *logQueue {
+	q := &logQueue{
+		conf:    conf,
+		metrics: m,
+		tasks:   make(chan func(), conf.getSize()),
+	}
+	for i := 0; i < conf.getWorkers(); i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+func (q *logQueue) work() {
+	defer q.wg.Done()
+	for task := range q.tasks {
+		task()
+	}
+}
+
+// submit enqueue task by the full policy, task is run inline when the queue is closed
+func (q *logQueue) submit(task func()) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		task()
+		return
+	}
+	policy := q.conf.getFullPolicy()
+	switch policy {
+	case LogQueueBlock:
+		q.tasks <- task
+		return
+	case LogQueueSample:
+		// keep part of entries once the queue is half full
+		if len(q.tasks) >= cap(q.tasks)/2 && rand.Float64() >= q.conf.getSampleRatio() {
+			q.dropped(policy)
+			return
+		}
+	}
+	select {
+	case q.tasks <- task:
+	default:
+		q.dropped(policy)
+	}
+}
+
+func (q *logQueue) dropped(policy string) {
+	if q.metrics != nil {
+		q.metrics.logQueueDropped.WithLabelValues(policy).Inc()
+	}
+}
+
+// close stop accepting tasks and wait queued tasks are written
+func (q *logQueue) close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		q.mu.Lock()
+		if !q.closed {
+			q.closed = true
+			close(q.tasks)
+		}
+		q.mu.Unlock()
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("flush access log, %d entries left: %w", len(q.tasks), ctx.Err())
+	}
+}
+
+// submitAccessLog write access log asynchronously on the queue of the app, inline for contexts without app
+func (c *Context) submitAccessLog(task func()) {
+	if c.logQueue == nil {
+		task()
+		return
+	}
+	c.logQueue.submit(task)
+}
+
+// flushAccessLog write queued access logs on shutdown
+func (e *App) flushAccessLog(ctx context.Context) error {
+	if e.logQueue == nil {
+		return nil
+	}
+	return e.logQueue.close(ctx)
+}
@@ -1,45 +1,286 @@
 package frame
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+
+	"github.com/imroc/req/v3"
+	"github.com/normastars/frame/version"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+)
+
+// histogram names, keys of metrics.buckets
+const (
+	metricRequestDuration  = "request_duration_seconds"
+	metricGRPCDuration     = "grpc_request_duration_seconds"
+	metricSendHTTPDuration = "send_http_requests_duration_seconds"
 )
 
-// var r = prometheus.NewRegistry()
+var histogramNames = []string{metricRequestDuration, metricGRPCDuration, metricSendHTTPDuration}
+
+// MetricsConfig prometheus metrics config
+type MetricsConfig struct {
+	Namespace      string               `json:"namespace"`                                                                // metric name prefix, eg: demo_request_duration_seconds
+	Subsystem      string               `json:"subsystem"`                                                                // added after the namespace
+	Version        string               `json:"version"`                                                                  // version label, default git commit
+	ConstLabels    map[string]string    `json:"const_labels" yaml:"const_labels" mapstructure:"const_labels"`             // labels of all metrics, project, env and version are added when not empty
+	Buckets        map[string][]float64 `json:"buckets"`                                                                  // seconds, by histogram name, default 0.05 ~ 20
+	MaxLabelValues int                  `json:"max_label_values" yaml:"max_label_values" mapstructure:"max_label_values"` // distinct values of url/host/path labels, later values are collapsed to "other", default 1000
+}
+
+// Validate check metrics config
+func (mc MetricsConfig) Validate() []error {
+	var errs []error
+	for name, buckets := range mc.Buckets {
+		if !slices.Contains(histogramNames, name) {
+			errs = append(errs, fmt.Errorf("metrics.buckets histogram %s is unknown, choose one of: request_duration_seconds/grpc_request_duration_seconds/send_http_requests_duration_seconds", name))
+		}
+		for i := range buckets {
+			if i > 0 && buckets[i] <= buckets[i-1] {
+				errs = append(errs, fmt.Errorf("please fill in the correct metrics.buckets %s in the configuration file, buckets must be in increasing order", name))
+				break
+			}
+		}
+	}
+	for name := range mc.ConstLabels {
+		if !model.LabelName(name).IsValid() {
+			errs = append(errs, fmt.Errorf("please fill in the correct metrics.const_labels in the configuration file, %s is not a valid label name", name))
+		}
+	}
+	return errs
+}
+
+func (mc MetricsConfig) getBuckets(name string) []float64 {
+	if b, ok := mc.Buckets[name]; ok && len(b) > 0 {
+		return b
+	}
+	return defaultMetricBuckets
+}
 
-func init() {
-	prometheus.MustRegister(prometheusRequestDuration)
-	prometheus.MustRegister(prometheusRequestBusCounter)
-	prometheus.MustRegister(sendHTTPRequests, sendHTTPRequestsDuration)
+func (mc MetricsConfig) getMaxLabelValues() int {
+	if mc.MaxLabelValues <= 0 {
+		return defaultMetricMaxLabelValues
+	}
+	return mc.MaxLabelValues
 }
 
-// Prometheus metrics
-var (
-	prometheusRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "request_duration_seconds",
-		Help:    "HTTP request duration in seconds.",
-		Buckets: []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 20000}, // ms
+// constLabels project, env, version and metrics.const_labels
+func (c *Config) constLabels() prometheus.Labels {
+	labels := prometheus.Labels{}
+	ver := c.Metrics.Version
+	if ver == "" {
+		ver = version.GitCommit
+	}
+	for k, v := range map[string]string{"project": c.Project, "env": c.Env, "version": ver} {
+		if v != "" {
+			labels[k] = v
+		}
+	}
+	for k, v := range c.Metrics.ConstLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// metrics prometheus collectors of one app, registered on its own registry
+type metrics struct {
+	registry   *prometheus.Registry
+	registerer prometheus.Registerer // carry the const labels
+
+	requestDuration     *prometheus.HistogramVec
+	requestBusCounter   *prometheus.CounterVec
+	requestPanicCounter *prometheus.CounterVec
+	wsConnections       *prometheus.GaugeVec
+	wsMessages          *prometheus.CounterVec
+	grpcDuration        *prometheus.HistogramVec
+	grpcCounter         *prometheus.CounterVec
+	logQueueDropped     *prometheus.CounterVec
+	sendHTTPRequests    *prometheus.CounterVec
+	sendHTTPDuration    *prometheus.HistogramVec
+
+	// cardinality guards of labels
+	routes      *labelGuard
+	clientHosts *labelGuard
+	clientPaths *labelGuard
+}
+
+func newMetrics(conf *Config) *metrics {
+	mc := conf.Metrics
+	ns, sub := mc.Namespace, mc.Subsystem
+	m := &metrics{
+		registry:    prometheus.NewRegistry(),
+		routes:      newLabelGuard("url", mc.getMaxLabelValues()),
+		clientHosts: newLabelGuard("host", mc.getMaxLabelValues()),
+		clientPaths: newLabelGuard("path", mc.getMaxLabelValues()),
+	}
+	m.registerer = prometheus.WrapRegistererWith(conf.constLabels(), m.registry)
+
+	m.requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      metricRequestDuration,
+		Help:      "HTTP request duration in seconds.",
+		Buckets:   mc.getBuckets(metricRequestDuration),
 	}, []string{"url", "code", "method"})
 
-	prometheusRequestBusCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "request_buss_count",
-		Help: "HTTP request business code count.",
+	m.requestBusCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "request_buss_count",
+		Help:      "HTTP request business code count.",
 	}, []string{"url", "bus_code", "method"})
-)
 
-var (
-	sendHTTPRequests = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "send_http_requests_total",
-			Help: "Number of the http requests sent since the server started",
-		},
-		[]string{"method", "host", "path", "code"},
-	)
-	sendHTTPRequestsDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "send_http_requests_duration_seconds",
-			Help:    "Duration in seconds to send http requests",
-			Buckets: []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 20000}, // ms
-		},
-		[]string{"method", "host", "path", "code"},
-	)
-)
+	m.requestPanicCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "request_panic_total",
+		Help:      "HTTP request panic recovered count.",
+	}, []string{"url", "method"})
+
+	m.wsConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "websocket_connections",
+		Help:      "Number of open websocket connections.",
+	}, []string{"url"})
+
+	m.wsMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "websocket_messages_total",
+		Help:      "Websocket messages count, direction in/out.",
+	}, []string{"url", "direction"})
+
+	m.grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      metricGRPCDuration,
+		Help:      "gRPC request duration in seconds.",
+		Buckets:   mc.getBuckets(metricGRPCDuration),
+	}, []string{"method", "code"})
+
+	m.grpcCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC request count by status code.",
+	}, []string{"method", "code"})
+
+	m.logQueueDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "access_log_dropped_total",
+		Help:      "Access log entries dropped when the log queue is full, by full policy.",
+	}, []string{"policy"})
+
+	m.sendHTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "send_http_requests_total",
+		Help:      "Number of the http requests sent since the server started",
+	}, []string{"method", "host", "path", "code"})
+
+	m.sendHTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      metricSendHTTPDuration,
+		Help:      "Duration in seconds to send http requests",
+		Buckets:   mc.getBuckets(metricSendHTTPDuration),
+	}, []string{"method", "host", "path", "code"})
+
+	m.registerer.MustRegister(m.requestDuration, m.requestBusCounter, m.requestPanicCounter)
+	m.registerer.MustRegister(m.wsConnections, m.wsMessages)
+	m.registerer.MustRegister(m.grpcDuration, m.grpcCounter)
+	m.registerer.MustRegister(m.sendHTTPRequests, m.sendHTTPDuration)
+	m.registerer.MustRegister(m.logQueueDropped)
+	return m
+}
+
+// handler metrics of the app and metrics registered on prometheus.DefaultRegisterer, eg: go and process metrics
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(prometheus.Gatherers{m.registry, prometheus.DefaultGatherer}, promhttp.HandlerOpts{})
+}
+
+// ReqMetricMiddleware count requests of a req client with the metrics of the app,
+// eg: req.C().OnAfterResponse(app.ReqMetricMiddleware())
+func (e *App) ReqMetricMiddleware() req.ResponseMiddleware {
+	return e.metrics.reqMiddleware
+}
+
+// Registerer register custom metrics of the app, they carry the const labels and are served by the metrics server
+func (e *App) Registerer() prometheus.Registerer {
+	return e.metrics.registerer
+}
+
+// routeLabel url label of server metrics, the matched route template, eg: /users/:id
+func routeLabel(fullPath string) string {
+	if fullPath == "" {
+		return unmatchedRouteLabel
+	}
+	return fullPath
+}
+
+type metricPathKey struct{}
+
+// WithMetricPath ctx carrying the path label of http client metrics, used instead of the request path,
+// eg: c.DoHTTP().R().SetContext(frame.WithMetricPath(c.WithTraceContext(), "/users/:id")).Get(url)
+func WithMetricPath(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, metricPathKey{}, path)
+}
+
+// clientPathLabel path label of http client metrics: the path of WithMetricPath,
+// the url template when path params are used, eg: /users/{id}, or the request path
+func clientPathLabel(c *req.Client, r *req.Request) string {
+	if p, ok := r.Context().Value(metricPathKey{}).(string); ok && p != "" {
+		return p
+	}
+	if len(r.PathParams) > 0 || (c != nil && len(c.PathParams) > 0) {
+		if u, err := url.Parse(r.RawURL); err == nil {
+			return u.Path
+		}
+	}
+	return r.URL.Path
+}
+
+// labelGuard limit distinct values of a label, values after the limit are collapsed to "other"
+type labelGuard struct {
+	mu     sync.RWMutex
+	name   string
+	limit  int
+	values map[string]struct{}
+	warn   sync.Once
+}
+
+func newLabelGuard(name string, limit int) *labelGuard {
+	return &labelGuard{name: name, limit: limit, values: map[string]struct{}{}}
+}
+
+// value v when it is known or the limit is not reached
+func (g *labelGuard) value(v string) string {
+	g.mu.RLock()
+	_, ok := g.values[v]
+	g.mu.RUnlock()
+	if ok {
+		return v
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.values[v]; ok {
+		return v
+	}
+	if len(g.values) >= g.limit {
+		g.warn.Do(func() {
+			logrus.Warnf("metric label %s reached %d values, new values are collapsed to %s\n", g.name, g.limit, collapsedLabel)
+		})
+		return collapsedLabel
+	}
+	g.values[v] = struct{}{}
+	return v
+}
@@ -0,0 +1,124 @@
+package frame
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/imroc/req/v3"
+)
+
+func TestLabelGuard(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		values []string
+		want   []string
+	}{
+		{name: "under limit", limit: 3, values: []string{"/a", "/b", "/a"}, want: []string{"/a", "/b", "/a"}},
+		{name: "collapsed after limit", limit: 2, values: []string{"/a", "/b", "/c", "/d"}, want: []string{"/a", "/b", collapsedLabel, collapsedLabel}},
+		{name: "known values kept after limit", limit: 1, values: []string{"/a", "/b", "/a"}, want: []string{"/a", collapsedLabel, "/a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newLabelGuard("url", tt.limit)
+			for i, v := range tt.values {
+				if got := g.value(v); got != tt.want[i] {
+					t.Errorf("value(%v) = %v, want %v", v, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLabelGuardConcurrent(t *testing.T) {
+	const limit = 10
+	g := newLabelGuard("url", limit)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				g.value("/" + strconv.Itoa(i*100+j))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := len(g.values); n != limit {
+		t.Errorf("labelGuard kept %d values, want %d", n, limit)
+	}
+}
+
+func TestRouteLabel(t *testing.T) {
+	if got := routeLabel("/users/:id"); got != "/users/:id" {
+		t.Errorf("routeLabel() = %v, want /users/:id", got)
+	}
+	if got := routeLabel(""); got != unmatchedRouteLabel {
+		t.Errorf("routeLabel() = %v, want %v", got, unmatchedRouteLabel)
+	}
+}
+
+func TestClientPathLabel(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(c *req.Client) *req.Request
+		sent  string // path of the url sent, path params are replaced
+		want  string
+	}{
+		{
+			name: "request path",
+			build: func(c *req.Client) *req.Request {
+				return c.R().SetURL("http://localhost/users/1?a=1")
+			},
+			sent: "/users/1",
+			want: "/users/1",
+		},
+		{
+			name: "path params of the request",
+			build: func(c *req.Client) *req.Request {
+				return c.R().SetURL("http://localhost/users/{id}").SetPathParam("id", "1")
+			},
+			sent: "/users/1",
+			want: "/users/{id}",
+		},
+		{
+			name: "path params of the client",
+			build: func(c *req.Client) *req.Request {
+				c.SetCommonPathParam("org", "o1")
+				return c.R().SetURL("http://localhost/orgs/{org}/users")
+			},
+			sent: "/orgs/o1/users",
+			want: "/orgs/{org}/users",
+		},
+		{
+			name: "metric path of the context",
+			build: func(c *req.Client) *req.Request {
+				return c.R().SetURL("http://localhost/users/{id}").SetPathParam("id", "1").
+					SetContext(WithMetricPath(context.Background(), "/users/:id"))
+			},
+			sent: "/users/1",
+			want: "/users/:id",
+		},
+		{
+			name: "empty metric path",
+			build: func(c *req.Client) *req.Request {
+				return c.R().SetURL("http://localhost/users/1").SetContext(WithMetricPath(context.Background(), ""))
+			},
+			sent: "/users/1",
+			want: "/users/1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := req.C()
+			r := tt.build(c)
+			r.URL = &url.URL{Scheme: "http", Host: "localhost", Path: tt.sent}
+			if got := clientPathLabel(c, r); got != tt.want {
+				t.Errorf("clientPathLabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
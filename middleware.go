@@ -5,29 +5,30 @@ import "github.com/gin-gonic/gin"
 // HandlerFunc freme middleware
 type HandlerFunc func(*Context)
 
-// Use use middleware
+// Use use middleware on all http servers
 func (e *App) Use(middleware ...HandlerFunc) {
 	if len(middleware) > 0 {
-		for i := range middleware {
-			e.Engine.Use(e.convert2GinHandlerFunc(middleware[i]))
+		for _, s := range e.Servers() {
+			s.Use(middleware...)
+		}
+		if len(e.servers) <= 0 {
+			e.defaultServer.Use(middleware...)
 		}
 	}
 }
 
-func (e *App) convert2FrameContext(c *gin.Context) *Context {
-	return &Context{
-		Gtx:          c,
-		config:       e.config,
-		dbClients:    e.dbClients,
-		redisClients: e.redisClients,
-		Entry:        e.getLogEntry(c),
-		httpClient:   e.getHTTPClient(c),
+func (e *App) convert2GinHandlerFunc(h HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h(e.getContext(c))
 	}
 }
 
-func (e *App) convert2GinHandlerFunc(h HandlerFunc) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx := e.convert2FrameContext(c)
-		h(ctx)
+// wrapHandlers convert frame handlers to gin handlers,
+// the whole chain share one *Context
+func (e *App) wrapHandlers(handlers []HandlerFunc) []gin.HandlerFunc {
+	list := make([]gin.HandlerFunc, 0, len(handlers))
+	for i := range handlers {
+		list = append(list, e.convert2GinHandlerFunc(handlers[i]))
 	}
+	return list
 }
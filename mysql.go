@@ -3,6 +3,7 @@ package frame
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -38,6 +39,11 @@ func newMySQLServers(conf *Config) {
 		if len(conf.Mysql.Configs) > 0 && conf.Mysql.Enable {
 			for _, v := range conf.Mysql.Configs {
 				conn := open(conf.LogLevel, conf.LogMode, v)
+				if conn != nil && conf.Tracing.Enable {
+					if err := conn.Use(gormTracing{}); err != nil {
+						logrus.Errorln(err)
+					}
+				}
 				if conn != nil {
 					// add connection map
 					dbMultiConn.clients[v.Name] = conn
@@ -47,26 +53,43 @@ func newMySQLServers(conf *Config) {
 	})
 }
 
+// closeMySQLServers close all mysql connection pools
+func closeMySQLServers() error {
+	var errs []error
+	for name, conn := range dbMultiConn.clients {
+		sqlDB, err := conn.DB()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mysql %s: %w", name, err))
+			continue
+		}
+		if err := sqlDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("mysql %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func open(logLevel, logMode string, item MySQLConfigItem) *gorm.DB {
 	if !item.Enable {
 		return nil
 	}
 	dsn := fmt.Sprintf("%s:%s@(%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", item.User, item.Password, item.Host, item.Database)
-	// Initialize a new logger instance
-	l := newLoggerLevel(logLevel, logMode)
+	// shared sql logger
+	l := NamedLogger(LoggerSQL)
 	// Set the GORM logger to the new logger instance
 	slowSec := 0
 	if item.SlowThresholdSec > 0 {
 		slowSec = item.SlowThresholdSec
 	}
-	dbLogger := logger.New(
+	// sql of clients and auto migrate is redacted like ctx.GetDB
+	dbLogger := redactGormLogger{logger.New(
 		l,
 		logger.Config{
 			SlowThreshold:             time.Duration(slowSec) * time.Second,
 			LogLevel:                  log2gormLevel(logLevel),
 			IgnoreRecordNotFoundError: true,
 		},
-	)
+	)}
 	dbConn, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: dbLogger})
 	if err == nil {
 		return dbConn
@@ -79,7 +102,7 @@ func open(logLevel, logMode string, item MySQLConfigItem) *gorm.DB {
 			logrus.Fatalln(err)
 		}
 		// retry connection mysql
-		dbConn, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+		dbConn, err = gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: dbLogger})
 		if err != nil {
 			logrus.Fatalln(err)
 		}
@@ -108,12 +131,11 @@ func createDatabaseSQL(database string) string {
 }
 
 type gormLogger struct {
-	Log     *logrus.Logger
 	Disable bool
 }
 
 func newGormLogger(config *Config) logger.Interface {
-	return &gormLogger{Log: NewLogger(config), Disable: config.Mysql.DisableReqLog}
+	return &gormLogger{Disable: config.Mysql.DisableReqLog}
 }
 
 func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
@@ -124,44 +146,38 @@ func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{})
 	if l.Disable {
 		return
 	}
-	l.Log.WithFields(logrus.Fields{
-		TraceIDKey: getTraceIDFromContext(ctx),
-	}).Infof(msg, data...)
+	traceEntry(LoggerSQL, getTraceIDFromContext(ctx)).Info(getRedactor().text(fmt.Sprintf(msg, data...)))
 }
 
 func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
 	if l.Disable {
 		return
 	}
-	l.Log.WithFields(logrus.Fields{
-		TraceIDKey: getTraceIDFromContext(ctx),
-	}).Warnf(msg, data...)
+	traceEntry(LoggerSQL, getTraceIDFromContext(ctx)).Warn(getRedactor().text(fmt.Sprintf(msg, data...)))
 }
 
 func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
 	if l.Disable {
 		return
 	}
-	l.Log.WithFields(logrus.Fields{
-		TraceIDKey: getTraceIDFromContext(ctx),
-	}).Errorf(msg, data...)
+	traceEntry(LoggerSQL, getTraceIDFromContext(ctx)).Error(getRedactor().text(fmt.Sprintf(msg, data...)))
 }
 
 func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
 	if l.Disable {
 		return
 	}
+	sql, rows := fc()
+	sql = getRedactor().text(sql)
 	if err != nil {
-		l.Log.WithFields(logrus.Fields{
-			TraceIDKey: getTraceIDFromContext(ctx),
+		traceEntry(LoggerSQL, getTraceIDFromContext(ctx)).WithFields(logrus.Fields{
 			"duration": time.Since(begin).Milliseconds(),
 			"error":    err.Error(),
-		}).Error(fc())
+		}).Error(sql, rows)
 	} else {
-		l.Log.WithFields(logrus.Fields{
-			TraceIDKey: getTraceIDFromContext(ctx),
+		traceEntry(LoggerSQL, getTraceIDFromContext(ctx)).WithFields(logrus.Fields{
 			"duration": time.Since(begin).Milliseconds(), //
-		}).Infoln(fc())
+		}).Infoln(sql, rows)
 	}
 }
 
@@ -0,0 +1,461 @@
+package frame
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/normastars/frame/version"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// routeInfo route registered by App/Server/RouterGroup
+type routeInfo struct {
+	Server  string
+	Method  string
+	Path    string
+	Handler string
+	meta    *handlerMeta
+}
+
+// handlerMeta request and response types of a typed handler
+type handlerMeta struct {
+	req  reflect.Type
+	resp reflect.Type
+	fn   string
+}
+
+type routeTable struct {
+	sync.RWMutex
+	list []routeInfo
+}
+
+// add route of server, the request and response types of a typed handler are kept with the route
+func (rt *routeTable) add(server, method, path string, handlers []HandlerFunc) {
+	if len(handlers) <= 0 {
+		return
+	}
+	// the last handler is the real handler
+	h := handlers[len(handlers)-1]
+	ri := routeInfo{Server: server, Method: method, Path: path, Handler: handlerName(h)}
+	if meta := describeHandler(h); meta != nil {
+		ri.meta = meta
+		ri.Handler = meta.fn
+	}
+	rt.Lock()
+	defer rt.Unlock()
+	rt.list = append(rt.list, ri)
+}
+
+func (rt *routeTable) routes() []routeInfo {
+	rt.RLock()
+	defer rt.RUnlock()
+	return append([]routeInfo(nil), rt.list...)
+}
+
+// typedHandlerName func name of the handlers created by Handle
+var typedHandlerName = handlerName(Handle(func(*Context, *struct{}) (*struct{}, ErrorMsg) { return nil, nil }))
+
+// describeHandler meta of a handler created by Handle, other handlers are never called
+func describeHandler(h HandlerFunc) *handlerMeta {
+	if handlerName(h) != typedHandlerName {
+		return nil
+	}
+	meta := &handlerMeta{}
+	h(&Context{describe: meta})
+	return meta
+}
+
+func handlerName(h interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if f == nil {
+		return ""
+	}
+	return f.Name()
+}
+
+// openAPIDoc build openapi 3 document from registered routes
+func (e *App) openAPIDoc() map[string]interface{} {
+	conf := e.config.HTTPServer.OpenAPI
+	b := &openAPIBuilder{schemas: map[string]interface{}{}, names: map[reflect.Type]string{}}
+	paths := map[string]map[string]interface{}{}
+	for _, r := range e.routes.routes() {
+		if r.Method == http.MethodConnect {
+			continue
+		}
+		p := ginPath2OpenAPI(r.Path)
+		if paths[p] == nil {
+			paths[p] = map[string]interface{}{}
+		}
+		paths[p][strings.ToLower(r.Method)] = b.operation(r)
+	}
+	b.schemas["Response"] = b.envelope(nil)
+	title := conf.Title
+	if title == "" {
+		title = e.config.Project
+	}
+	ver := conf.Version
+	if ver == "" {
+		ver = version.GitCommit
+	}
+	if ver == "" {
+		ver = defaultOpenAPIVersion
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": ver,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+		},
+	}
+}
+
+var ginParamRegexp = regexp.MustCompile(`[:*]([^/]+)`)
+
+// ginPath2OpenAPI /users/:id => /users/{id}
+func ginPath2OpenAPI(p string) string {
+	return ginParamRegexp.ReplaceAllString(p, "{$1}")
+}
+
+type openAPIBuilder struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+func (b *openAPIBuilder) operation(r routeInfo) map[string]interface{} {
+	op := map[string]interface{}{
+		"tags":        []string{r.Server},
+		"summary":     shortFuncName(r.Handler),
+		"operationId": strings.ToLower(r.Method) + operationIDRegexp.ReplaceAllString(r.Path, "_"),
+	}
+	var params []interface{}
+	// path params always exist even if handler is untyped
+	for _, m := range ginParamRegexp.FindAllStringSubmatch(r.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	var data reflect.Type
+	responses := map[string]interface{}{}
+	if r.meta != nil {
+		params = mergeParameters(params, b.parameters(r.meta.req))
+		if body := b.requestBody(r.meta.req); body != nil && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": body},
+				},
+			}
+		}
+		data = r.meta.resp
+		responses["400"] = jsonResponse("invalid request params", refSchema("Response"))
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	responses["200"] = jsonResponse("success", b.envelope(data))
+	responses["default"] = jsonResponse("business error", refSchema("Response"))
+	op["responses"] = responses
+	return op
+}
+
+// mergeParameters params of the req type replace route params with the same name and location, others are kept
+func mergeParameters(route, typed []interface{}) []interface{} {
+	key := func(p interface{}) string {
+		m := p.(map[string]interface{})
+		return m["in"].(string) + ":" + m["name"].(string)
+	}
+	defined := make(map[string]bool, len(typed))
+	for _, p := range typed {
+		defined[key(p)] = true
+	}
+	var params []interface{}
+	for _, p := range route {
+		if !defined[key(p)] {
+			params = append(params, p)
+		}
+	}
+	return append(params, typed...)
+}
+
+var operationIDRegexp = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+func shortFuncName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+func jsonResponse(desc string, schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": desc,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+func refSchema(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// envelope Response schema with data
+func (b *openAPIBuilder) envelope(data reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{
+		"code":     map[string]interface{}{"type": "string", "example": successCode},
+		"message":  map[string]interface{}{"type": "string", "example": successMsg},
+		"time":     map[string]interface{}{"type": "string", "format": "date-time"},
+		"trace_id": map[string]interface{}{"type": "string"},
+	}
+	if data != nil {
+		props["data"] = b.schemaOf(data)
+	}
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+// parameters path/query/header params from uri/form/header tags
+func (b *openAPIBuilder) parameters(t reflect.Type) []interface{} {
+	var params []interface{}
+	eachField(t, func(f reflect.StructField) {
+		for _, in := range []struct{ tag, in string }{{"uri", "path"}, {"form", "query"}, {"header", "header"}} {
+			name := tagName(f, in.tag)
+			if name == "" {
+				continue
+			}
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       in.in,
+				"required": in.in == "path" || isRequired(f),
+				"schema":   b.schemaOf(f.Type),
+			})
+		}
+	})
+	return params
+}
+
+// requestBody body schema, fields with uri/form/header tag but without json tag are excluded
+func (b *openAPIBuilder) requestBody(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	eachField(t, func(f reflect.StructField) {
+		name, ok := jsonName(f)
+		if !ok {
+			return
+		}
+		if f.Tag.Get("json") == "" && (tagName(f, "uri") != "" || tagName(f, "form") != "" || tagName(f, "header") != "") {
+			return
+		}
+		props[name] = b.schemaOf(f.Type)
+		if isRequired(f) {
+			required = append(required, name)
+		}
+	})
+	if len(props) <= 0 {
+		return nil
+	}
+	s := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf reflect go type to openapi schema, named structs are put into components
+func (b *openAPIBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return b.structSchema(t)
+	default:
+		// interface{}
+		return map[string]interface{}{}
+	}
+}
+
+func (b *openAPIBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	if t.Name() == "" {
+		return b.objectSchema(t)
+	}
+	if name, ok := b.names[t]; ok {
+		return refSchema(name)
+	}
+	name := schemaName(t)
+	for i := 2; b.schemas[name] != nil; i++ {
+		name = schemaName(t) + "_" + strconv.Itoa(i)
+	}
+	b.names[t] = name
+	// placeholder for recursive types
+	b.schemas[name] = map[string]interface{}{}
+	b.schemas[name] = b.objectSchema(t)
+	return refSchema(name)
+}
+
+func (b *openAPIBuilder) objectSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	eachField(t, func(f reflect.StructField) {
+		name, ok := jsonName(f)
+		if !ok {
+			return
+		}
+		props[name] = b.schemaOf(f.Type)
+		if isRequired(f) {
+			required = append(required, name)
+		}
+	})
+	s := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+var schemaNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.]+`)
+
+// schemaName PageResults, generic type Page[pkg.User] => Page_User
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.Index(name, "["); i >= 0 {
+		args := strings.Split(strings.TrimSuffix(name[i+1:], "]"), ",")
+		for j := range args {
+			args[j] = shortFuncName(args[j])
+			if k := strings.LastIndex(args[j], "."); k >= 0 {
+				args[j] = args[j][k+1:]
+			}
+		}
+		name = name[:i] + "_" + strings.Join(args, "_")
+	}
+	return schemaNameRegexp.ReplaceAllString(name, "_")
+}
+
+// eachField walk exported fields, embedded structs are flattened
+func eachField(t reflect.Type, fn func(f reflect.StructField)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			eachField(f.Type, fn)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		fn(f)
+	}
+}
+
+func tagName(f reflect.StructField, tag string) string {
+	name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.SplitN(tag, ",", 2)[0]; name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+func isRequired(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// openAPIHTML swagger ui page of the spec, assets are served from swaggerFiles.FS
+//
+//go:embed openapi.html
+var openAPIHTML string
+
+var openAPITemplate = template.Must(template.New("openapi").Parse(openAPIHTML))
+
+// mountOpenAPI serve openapi document and swagger ui on http_server.openapi.server
+func (e *App) mountOpenAPI(mux *http.ServeMux, server *Server) {
+	conf := e.config.HTTPServer.OpenAPI
+	specPath, uiPath := conf.getPath(), conf.getUIPath()
+	assetPath := strings.TrimSuffix(uiPath, "/") + "/"
+	spec := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(e.openAPIDoc())
+	}
+	ui := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = openAPITemplate.Execute(w, map[string]string{"Title": e.config.Project, "SpecPath": specPath, "AssetPath": assetPath})
+	}
+	files := http.StripPrefix(assetPath, http.FileServer(http.FS(swaggerFiles.FS)))
+	// index.html of the dist loads the petstore spec, so the page is always rendered by frame
+	assets := func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, assetPath) {
+		case "", "index.html":
+			ui(w, r)
+		default:
+			files.ServeHTTP(w, r)
+		}
+	}
+	if mux != nil {
+		mux.HandleFunc(specPath, spec)
+		if uiPath != assetPath {
+			mux.HandleFunc(uiPath, ui)
+		}
+		mux.HandleFunc(assetPath, assets)
+		return
+	}
+	server.Engine.GET(specPath, gin.WrapF(spec))
+	if uiPath != assetPath {
+		server.Engine.GET(uiPath, gin.WrapF(ui))
+	}
+	server.Engine.GET(assetPath+"*filepath", gin.WrapF(assets))
+}
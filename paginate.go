@@ -0,0 +1,310 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pagination query params
+const (
+	pageParam     = "page"
+	pageSizeParam = "page_size"
+	sortParam     = "sort"
+	cursorParam   = "cursor"
+)
+
+// PageOptions ctx.Paginate options, zero value fields use http_server.pagination config
+type PageOptions struct {
+	DefaultPageSize int               // default http_server.pagination.default_page_size
+	MaxPageSize     int               // default http_server.pagination.max_page_size
+	SortFields      []string          // columns the client can sort by, empty means the sort param is rejected
+	DefaultSort     string            // used without sort param, eg: -created_at,id, "-" means desc, not limited by SortFields
+	Filters         map[string]string // query param => column, equal filter, repeated param use IN
+	Cursor          string            // keyset pagination column, eg: id, sort is ignored in cursor mode
+	CursorDesc      bool              // cursor column order desc
+}
+
+// PaginationConfig pagination default config
+type PaginationConfig struct {
+	DefaultPageSize int `json:"default_page_size" yaml:"default_page_size" mapstructure:"default_page_size"` // default 20
+	MaxPageSize     int `json:"max_page_size" yaml:"max_page_size" mapstructure:"max_page_size"`             // default 100
+}
+
+func (pc PaginationConfig) getDefaultPageSize() int {
+	if pc.DefaultPageSize <= 0 {
+		return defaultPageSize
+	}
+	return pc.DefaultPageSize
+}
+
+func (pc PaginationConfig) getMaxPageSize() int {
+	if pc.MaxPageSize <= 0 {
+		return defaultMaxPageSize
+	}
+	return pc.MaxPageSize
+}
+
+// columnRegexp sort/cursor column name, eg: id, users.created_at
+var columnRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+type pageQuery struct {
+	page     int
+	pageSize int
+	orders   []clause.OrderByColumn
+	cursor   interface{}
+}
+
+// Paginate read page/page_size/sort (or cursor) and filter params, query db into out and reply HTTPListSuccess.
+// out must be a pointer to slice, eg: &[]User{}, invalid params reply 400, query failed reply 500,
+// the replied error is returned.
+// eg: ctx.Paginate(ctx.GetDB().Model(&User{}), &users, &frame.PageOptions{SortFields: []string{"id", "created_at"}})
+func (ctx *Context) Paginate(db *gorm.DB, out interface{}, opts ...*PageOptions) error {
+	opt := ctx.pageOptions(opts...)
+	q, err := ctx.parsePageQuery(opt)
+	if err != nil {
+		ctx.Error(ErrInvalidParams.Wrap(err).WithDetail(err.Error()))
+		return err
+	}
+	// new session, count and find share the conditions
+	db = db.Session(&gorm.Session{})
+	if db.Statement.Model == nil && db.Statement.Table == "" {
+		db = db.Model(out)
+	}
+	for param, column := range opt.Filters {
+		values := ctx.Gtx.QueryArray(param)
+		switch {
+		case len(values) == 1:
+			db = db.Where(clause.Eq{Column: clause.Column{Name: column}, Value: values[0]})
+		case len(values) > 1:
+			list := make([]interface{}, 0, len(values))
+			for _, v := range values {
+				list = append(list, v)
+			}
+			db = db.Where(clause.IN{Column: clause.Column{Name: column}, Values: list})
+		}
+	}
+	if opt.Cursor != "" {
+		return ctx.paginateCursor(db, out, opt, q)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		ctx.Error(ErrInternal.Wrap(err))
+		return err
+	}
+	find := db
+	for _, o := range q.orders {
+		find = find.Order(o)
+	}
+	if err := find.Offset((q.page - 1) * q.pageSize).Limit(q.pageSize).Find(out).Error; err != nil {
+		ctx.Error(ErrInternal.Wrap(err))
+		return err
+	}
+	ctx.HTTPListSuccess(&PageResults{
+		Total:    int(total),
+		Page:     q.page,
+		PageSize: q.pageSize,
+		Results:  out,
+	})
+	return nil
+}
+
+// paginateCursor keyset pagination, query page_size+1 rows to know whether there is a next page
+func (ctx *Context) paginateCursor(db *gorm.DB, out interface{}, opt *PageOptions, q *pageQuery) error {
+	column := clause.Column{Name: opt.Cursor}
+	if q.cursor != nil {
+		if opt.CursorDesc {
+			db = db.Where(clause.Lt{Column: column, Value: q.cursor})
+		} else {
+			db = db.Where(clause.Gt{Column: column, Value: q.cursor})
+		}
+	}
+	res := db.Order(clause.OrderByColumn{Column: column, Desc: opt.CursorDesc}).Limit(q.pageSize + 1).Find(out)
+	if res.Error != nil {
+		ctx.Error(ErrInternal.Wrap(res.Error))
+		return res.Error
+	}
+	var nextCursor string
+	rv := reflect.Indirect(reflect.ValueOf(out))
+	if rv.Kind() == reflect.Slice && rv.Len() > q.pageSize {
+		rv.Set(rv.Slice(0, q.pageSize))
+		next, err := ctx.encodeCursor(res, opt.Cursor, rv.Index(q.pageSize-1))
+		if err != nil {
+			ctx.Error(ErrInternal.Wrap(err))
+			return err
+		}
+		nextCursor = next
+	}
+	ctx.HTTPListSuccess(&PageResults{
+		PageSize:   q.pageSize,
+		Results:    out,
+		NextCursor: nextCursor,
+	})
+	return nil
+}
+
+// encodeCursor cursor column value of the last row, base64 json
+func (ctx *Context) encodeCursor(res *gorm.DB, column string, last reflect.Value) (string, error) {
+	if res.Statement.Schema == nil {
+		return "", errors.New("paginate cursor needs a gorm model")
+	}
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+	field := res.Statement.Schema.LookUpField(column)
+	if field == nil {
+		return "", fmt.Errorf("paginate cursor column %s can't find in %s", column, res.Statement.Schema.Name)
+	}
+	v, _ := field.ValueOf(ctx, last)
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(cursor string) (interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	// keep big int id
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch v.(type) {
+	case json.Number, string, bool:
+		return v, nil
+	}
+	return nil, errors.New("unsupported cursor value")
+}
+
+// pageOptions merge options with config defaults
+func (ctx *Context) pageOptions(opts ...*PageOptions) *PageOptions {
+	opt := &PageOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		cp := *opts[0]
+		opt = &cp
+	}
+	var conf PaginationConfig
+	if ctx.config != nil {
+		conf = ctx.config.HTTPServer.Pagination
+	}
+	if opt.DefaultPageSize <= 0 {
+		opt.DefaultPageSize = conf.getDefaultPageSize()
+	}
+	if opt.MaxPageSize <= 0 {
+		opt.MaxPageSize = conf.getMaxPageSize()
+	}
+	if opt.DefaultPageSize > opt.MaxPageSize {
+		opt.DefaultPageSize = opt.MaxPageSize
+	}
+	return opt
+}
+
+func (ctx *Context) parsePageQuery(opt *PageOptions) (*pageQuery, error) {
+	q := &pageQuery{page: 1, pageSize: opt.DefaultPageSize}
+	if v := ctx.Gtx.Query(pageSizeParam); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("%s must be a positive integer", pageSizeParam)
+		}
+		q.pageSize = n
+	}
+	// page_size over max use max
+	if q.pageSize > opt.MaxPageSize {
+		q.pageSize = opt.MaxPageSize
+	}
+	if opt.Cursor != "" {
+		if !columnRegexp.MatchString(opt.Cursor) {
+			return nil, fmt.Errorf("invalid cursor column %s", opt.Cursor)
+		}
+		if v := ctx.Gtx.Query(cursorParam); v != "" {
+			cursor, err := decodeCursor(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", cursorParam)
+			}
+			q.cursor = cursor
+		}
+		return q, nil
+	}
+	if v := ctx.Gtx.Query(pageParam); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("%s must be a positive integer", pageParam)
+		}
+		q.page = n
+	}
+	sort := ctx.Gtx.Query(sortParam)
+	if sort == "" {
+		sort = opt.DefaultSort
+	}
+	orders, err := parseSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	// default sort is set by the handler, only the sort param is limited by SortFields
+	if ctx.Gtx.Query(sortParam) != "" {
+		if err := checkSortFields(orders, opt.SortFields); err != nil {
+			return nil, err
+		}
+	}
+	q.orders = orders
+	return q, nil
+}
+
+// checkSortFields sort columns must be in whitelist, an empty whitelist allows no column
+func checkSortFields(orders []clause.OrderByColumn, whitelist []string) error {
+	for _, o := range orders {
+		if !inStrings(whitelist, o.Column.Name) {
+			return fmt.Errorf("%s field %s is not allowed", sortParam, o.Column.Name)
+		}
+	}
+	return nil
+}
+
+// parseSort parse sort param, eg: -created_at,id => created_at desc, id asc
+func parseSort(sort string) ([]clause.OrderByColumn, error) {
+	var orders []clause.OrderByColumn
+	for _, f := range strings.Split(sort, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		desc := false
+		switch f[0] {
+		case '-':
+			desc = true
+			f = f[1:]
+		case '+':
+			f = f[1:]
+		}
+		if !columnRegexp.MatchString(f) {
+			return nil, fmt.Errorf("invalid %s field %s", sortParam, f)
+		}
+		orders = append(orders, clause.OrderByColumn{Column: clause.Column{Name: f}, Desc: desc})
+	}
+	return orders, nil
+}
+
+func inStrings(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
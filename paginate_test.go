@@ -0,0 +1,101 @@
+package frame
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm/clause"
+)
+
+func TestParseSort(t *testing.T) {
+	order := func(name string, desc bool) clause.OrderByColumn {
+		return clause.OrderByColumn{Column: clause.Column{Name: name}, Desc: desc}
+	}
+	tests := []struct {
+		name    string
+		sort    string
+		want    []clause.OrderByColumn
+		wantErr bool
+	}{
+		{name: "empty", sort: ""},
+		{name: "asc", sort: "id", want: []clause.OrderByColumn{order("id", false)}},
+		{name: "desc and plus", sort: "-created_at,+id", want: []clause.OrderByColumn{order("created_at", true), order("id", false)}},
+		{name: "spaces and empty items", sort: " -id , ,name", want: []clause.OrderByColumn{order("id", true), order("name", false)}},
+		{name: "table column", sort: "users.id", want: []clause.OrderByColumn{order("users.id", false)}},
+		{name: "injection", sort: "id;drop table users", wantErr: true},
+		{name: "function", sort: "length(password)", wantErr: true},
+		{name: "only minus", sort: "-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSort(tt.sort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSortFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		sort      string
+		whitelist []string
+		wantErr   bool
+	}{
+		{name: "allowed", sort: "-created_at,id", whitelist: []string{"id", "created_at"}},
+		{name: "not allowed", sort: "password", whitelist: []string{"id"}, wantErr: true},
+		{name: "nil whitelist allows nothing", sort: "id", wantErr: true},
+		{name: "empty whitelist allows nothing", sort: "id", whitelist: []string{}, wantErr: true},
+		{name: "no sort", sort: "", whitelist: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := parseSort(tt.sort)
+			if err != nil {
+				t.Fatalf("parseSort() error = %v", err)
+			}
+			if err := checkSortFields(orders, tt.whitelist); (err != nil) != tt.wantErr {
+				t.Errorf("checkSortFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		name    string
+		cursor  string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "big int", cursor: encode("9007199254740993"), want: json.Number("9007199254740993")},
+		{name: "string", cursor: encode(`"2023-01-02T15:04:05Z"`), want: "2023-01-02T15:04:05Z"},
+		{name: "bool", cursor: encode("true"), want: true},
+		{name: "object", cursor: encode(`{"id":1}`), wantErr: true},
+		{name: "array", cursor: encode("[1]"), wantErr: true},
+		{name: "null", cursor: encode("null"), wantErr: true},
+		{name: "invalid json", cursor: encode("{"), wantErr: true},
+		{name: "invalid base64", cursor: "!!", wantErr: true},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte("1")), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCursor() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package frame
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ResponseMode error response format
+type ResponseMode string
+
+// response modes
+const (
+	// ResponseModeEnvelope {code,data,message,time,trace_id} envelope, default
+	ResponseModeEnvelope ResponseMode = "envelope"
+	// ResponseModeProblem RFC 7807 application/problem+json
+	ResponseModeProblem ResponseMode = "problem"
+)
+
+// MIMEProblemJSON RFC 7807 problem details content type
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem RFC 7807 problem details, code and trace_id are extension members
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+	TraceID  string `json:"trace_id,omitempty"`
+}
+
+// ResponseModeFunc set error response mode of a route group
+// eg: app.Group("/partner", frame.ResponseModeFunc(frame.ResponseModeProblem))
+func ResponseModeFunc(mode ResponseMode) HandlerFunc {
+	return func(c *Context) {
+		c.responseMode = mode
+		c.Gtx.Next()
+	}
+}
+
+// isProblemMode Accept: application/problem+json first, then route group mode, then http_server.response_mode
+func (c *Context) isProblemMode() bool {
+	if accept, ok := c.acceptsProblem(); ok {
+		return accept
+	}
+	if c.responseMode != "" {
+		return c.responseMode == ResponseModeProblem
+	}
+	return c.config != nil && c.config.HTTPServer.ResponseMode == ResponseModeProblem
+}
+
+// acceptsProblem true when Accept prefers application/problem+json to application/json,
+// false when it refuses it by q=0, ok is false when Accept doesn't decide
+func (c *Context) acceptsProblem() (accept bool, ok bool) {
+	if c.Gtx == nil || c.Gtx.Request == nil {
+		return false, false
+	}
+	list := parseAcceptQ(c.Gtx.GetHeader("Accept"))
+	for _, m := range list {
+		if m.mime == MIMEProblemJSON && m.q <= 0 {
+			return false, true
+		}
+	}
+	for _, m := range list {
+		switch m.mime {
+		case MIMEProblemJSON:
+			return true, true
+		case MIMEJSON:
+			return false, false
+		}
+	}
+	return false, false
+}
+
+// renderProblem reply errMsg as problem details
+func (c *Context) renderProblem(httpCode int, errMsg ErrorMsg) {
+	p := &Problem{
+		Type:    c.problemType(errMsg.GetCode()),
+		Title:   c.replyOf(errMsg),
+		Status:  httpCode,
+		Code:    errMsg.GetCode(),
+		TraceID: c.GetTraceID(),
+	}
+	if e, ok := errMsg.(*Error); ok && e.GetDetail() != "" {
+		// title is the reply of the code, detail is specific to this occurrence
+		p.Title = e.code.reply(c.acceptLanguages())
+		p.Detail = e.GetDetail()
+	}
+	if c.Gtx != nil && c.Gtx.Request != nil {
+		p.Instance = c.Gtx.Request.URL.RequestURI()
+	}
+	c.replied = true
+	c.replyCode = p.Code
+	c.replyMsg = p.Title
+	// contexts of NewContextNoGin have nothing to write to
+	if c.Gtx == nil {
+		return
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		c.Gtx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Gtx.Data(httpCode, MIMEProblemJSON, b)
+}
+
+// problemType http_server.problem_type_uri + code, default about:blank
+func (c *Context) problemType(code string) string {
+	base := c.config.HTTPServer.ProblemTypeURI
+	if base == "" {
+		return "about:blank"
+	}
+	return strings.TrimSuffix(base, "/") + "/" + code
+}
@@ -0,0 +1,31 @@
+package frame
+
+import "testing"
+
+func TestIsProblemMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		mode   ResponseMode
+		want   bool
+	}{
+		{name: "no accept", want: false},
+		{name: "problem", accept: "application/problem+json", want: true},
+		{name: "problem preferred", accept: "application/json;q=0.5, application/problem+json", want: true},
+		{name: "json preferred", accept: "application/problem+json;q=0.5, application/json", want: false},
+		{name: "json preferred in problem mode", accept: "application/problem+json;q=0.5, application/json", mode: ResponseModeProblem, want: true},
+		{name: "refused", accept: "application/problem+json;q=0", want: false},
+		{name: "refused in problem mode", accept: "application/json, application/problem+json;q=0", mode: ResponseModeProblem, want: false},
+		{name: "config mode", accept: "application/json", mode: ResponseModeProblem, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newAcceptContext(tt.accept)
+			c.config = &Config{}
+			c.config.HTTPServer.ResponseMode = tt.mode
+			if got := c.isProblemMode(); got != tt.want {
+				t.Errorf("isProblemMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
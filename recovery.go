@@ -0,0 +1,67 @@
+package frame
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+	"syscall"
+)
+
+// RecoveryFunc recover panic in frame handlers, log the stack with trace id
+// and reply http_server.recovery response
+func RecoveryFunc() HandlerFunc {
+	return func(c *Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			conf := c.config.HTTPServer.Recovery
+			if c.config.EnableMetric {
+				c.metrics.requestPanicCounter.WithLabelValues(c.metrics.routes.value(routeLabel(c.Gtx.FullPath())), c.Gtx.Request.Method).Inc()
+			}
+			l := c.Entry.WithField("panic", fmt.Sprint(err))
+			if !conf.DisableStack {
+				l = l.WithField("stack", string(debug.Stack()))
+			}
+			l.Errorln("panic recovered")
+			if isBrokenPipe(err) {
+				// connection is dead, can't write response
+				c.Gtx.Abort()
+				return
+			}
+			if !c.Gtx.Writer.Written() {
+				if conf.Code == "" && conf.Message == "" {
+					c.HTTPError(conf.getHTTPStatus(), ErrInternal.Wrap(fmt.Errorf("panic: %v", err)))
+				} else {
+					c.HTTPError2(conf.getHTTPStatus(), conf.getCode(), conf.getMessage(), fmt.Errorf("panic: %v", err))
+				}
+			}
+			c.Gtx.Abort()
+		}()
+		c.Gtx.Next()
+	}
+}
+
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var ne *net.OpError
+	if !errors.As(e, &ne) {
+		return false
+	}
+	var se *os.SyscallError
+	if errors.As(ne, &se) {
+		if errors.Is(se.Err, syscall.EPIPE) || errors.Is(se.Err, syscall.ECONNRESET) {
+			return true
+		}
+		msg := strings.ToLower(se.Error())
+		return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package frame
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/tidwall/gjson"
+	"gorm.io/gorm/logger"
+)
+
+// RedactConfig sensitive data redaction, applied to access logs, http client logs, sql logs, redis logs and tracing spans before they are written
+type RedactConfig struct {
+	Headers       []string `json:"headers"`                                                               // header names, default Authorization, Proxy-Authorization, Cookie, Set-Cookie
+	JSONPaths     []string `json:"json_paths" yaml:"json_paths" mapstructure:"json_paths"`                // gjson paths of json bodies, eg: password, user.id_card, cards.#.number
+	QueryParams   []string `json:"query_params" yaml:"query_params" mapstructure:"query_params"`          // query param names, case insensitive, eg: token
+	Patterns      []string `json:"patterns"`                                                              // regexps masked in bodies, sql and redis commands, eg: \b\d{13,19}\b
+	RedisCommands []string `json:"redis_commands" yaml:"redis_commands" mapstructure:"redis_commands"`    // commands whose values after the key are masked, eg: set, hset. AUTH is always masked
+	HideSQLValues bool     `json:"hide_sql_values" yaml:"hide_sql_values" mapstructure:"hide_sql_values"` // log sql with ? placeholders instead of bound values
+	Mask          string   `json:"mask"`                                                                  // default ***
+}
+
+// Validate check redact config
+func (rc RedactConfig) Validate() []error {
+	var errs []error
+	for _, p := range rc.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			errs = append(errs, fmt.Errorf("please fill in the correct redact.patterns in the configuration file, %q: %v", p, err))
+		}
+	}
+	return errs
+}
+
+// redactor compiled redact config
+type redactor struct {
+	headers       map[string]bool
+	query         map[string]bool
+	paths         []string
+	patterns      []*regexp.Regexp
+	redisCommands map[string]bool
+	hideSQLValues bool
+	mask          string
+}
+
+func newRedactor(conf RedactConfig) *redactor {
+	r := &redactor{
+		headers:       map[string]bool{},
+		query:         map[string]bool{},
+		paths:         conf.JSONPaths,
+		redisCommands: map[string]bool{},
+		hideSQLValues: conf.HideSQLValues,
+		mask:          conf.Mask,
+	}
+	if r.mask == "" {
+		r.mask = defaultRedactMask
+	}
+	headers := conf.Headers
+	if len(headers) == 0 {
+		headers = defaultRedactHeaders
+	}
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, q := range conf.QueryParams {
+		r.query[strings.ToLower(q)] = true
+	}
+	for _, p := range conf.Patterns {
+		// invalid patterns are reported by Validate
+		if re, err := regexp.Compile(p); err == nil {
+			r.patterns = append(r.patterns, re)
+		}
+	}
+	for _, c := range conf.RedisCommands {
+		r.redisCommands[strings.ToLower(c)] = true
+	}
+	return r
+}
+
+var redaction = struct {
+	sync.RWMutex
+	r *redactor
+}{r: newRedactor(RedactConfig{})}
+
+func setRedactConfig(conf RedactConfig) {
+	r := newRedactor(conf)
+	redaction.Lock()
+	defer redaction.Unlock()
+	redaction.r = r
+}
+
+func getRedactor() *redactor {
+	redaction.RLock()
+	defer redaction.RUnlock()
+	return redaction.r
+}
+
+// header copy of h with sensitive headers masked, patterns are masked in other values
+func (r *redactor) header(h http.Header) http.Header {
+	if len(h) == 0 {
+		return h
+	}
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if r.headers[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{r.mask}
+			continue
+		}
+		out[k] = r.values(v)
+	}
+	return out
+}
+
+// queryParams copy of q with sensitive params masked, patterns are masked in other values
+func (r *redactor) queryParams(q map[string][]string) map[string][]string {
+	if len(q) == 0 || (len(r.query) == 0 && len(r.patterns) == 0) {
+		return q
+	}
+	out := make(map[string][]string, len(q))
+	for k, v := range q {
+		if r.query[strings.ToLower(k)] {
+			masked := make([]string, len(v))
+			for i := range masked {
+				masked[i] = r.mask
+			}
+			out[k] = masked
+			continue
+		}
+		out[k] = r.values(v)
+	}
+	return out
+}
+
+// url raw url with the password, sensitive query params and patterns masked, keeps the order and encoding of the query
+func (r *redactor) url(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return r.text(raw)
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), r.mask)
+	}
+	if u.RawQuery != "" && len(r.query) > 0 {
+		pairs := strings.Split(u.RawQuery, "&")
+		for i, pair := range pairs {
+			key, _, _ := strings.Cut(pair, "=")
+			if k, err := url.QueryUnescape(key); err == nil && r.query[strings.ToLower(k)] {
+				pairs[i] = key + "=" + r.mask
+			}
+		}
+		u.RawQuery = strings.Join(pairs, "&")
+	}
+	return r.text(u.String())
+}
+
+func (r *redactor) values(v []string) []string {
+	if len(r.patterns) == 0 {
+		return v
+	}
+	out := make([]string, len(v))
+	for i := range v {
+		out[i] = r.text(v[i])
+	}
+	return out
+}
+
+// body mask json paths of json bodies, then patterns
+func (r *redactor) body(s string) string {
+	if s == "" {
+		return s
+	}
+	if len(r.paths) > 0 && gjson.Valid(s) {
+		s = r.jsonPaths(s)
+	}
+	return r.text(s)
+}
+
+// text mask patterns
+func (r *redactor) text(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, r.mask)
+	}
+	return s
+}
+
+// jsonPaths replace the raw values matched by json paths with the mask string
+func (r *redactor) jsonPaths(s string) string {
+	type span struct{ start, end int }
+	var spans []span
+	for _, path := range r.paths {
+		res := gjson.Get(s, path)
+		if !res.Exists() {
+			continue
+		}
+		if len(res.Indexes) > 0 {
+			for i, v := range res.Array() {
+				if i < len(res.Indexes) && res.Indexes[i] > 0 {
+					spans = append(spans, span{res.Indexes[i], res.Indexes[i] + len(v.Raw)})
+				}
+			}
+			continue
+		}
+		if res.Index > 0 {
+			spans = append(spans, span{res.Index, res.Index + len(res.Raw)})
+		}
+	}
+	if len(spans) == 0 {
+		return s
+	}
+	// drop matches nested in an earlier one, then replace from the end so offsets stay valid
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	kept := spans[:1]
+	for _, sp := range spans[1:] {
+		if sp.start >= kept[len(kept)-1].end {
+			kept = append(kept, sp)
+		}
+	}
+	mask := `"` + r.mask + `"`
+	for i := len(kept) - 1; i >= 0; i-- {
+		s = s[:kept[i].start] + mask + s[kept[i].end:]
+	}
+	return s
+}
+
+// logBody mask headers, query params and bodies of an access or http client log
+func (r *redactor) logBody(b *logBody) {
+	b.Extra.Req.Header = r.header(b.Extra.Req.Header)
+	b.Extra.Req.QueryParams = r.queryParams(b.Extra.Req.QueryParams)
+	b.Extra.Req.Body = r.body(b.Extra.Req.Body)
+	b.Extra.Resp.Body = r.body(b.Extra.Resp.Body)
+}
+
+// redisCmd command string with AUTH arguments and values of configured commands masked
+func (r *redactor) redisCmd(cmd redis.Cmder) string {
+	args := cmd.Args()
+	name := strings.ToLower(cmd.Name())
+	// AUTH password, AUTH user password, HELLO 3 AUTH user password
+	maskFrom := -1
+	switch {
+	case name == "auth":
+		maskFrom = 1
+	case name == "hello":
+		maskFrom = 2
+	case r.redisCommands[name]:
+		maskFrom = 2
+	}
+	ss := make([]string, 0, len(args))
+	for i, arg := range args {
+		if maskFrom > 0 && i >= maskFrom {
+			ss = append(ss, r.mask)
+			continue
+		}
+		ss = append(ss, fmt.Sprint(arg))
+	}
+	return r.text(strings.Join(ss, " "))
+}
+
+// ParamsFilter implement gorm.ParamsFilter, bound values are dropped when redact.hide_sql_values is set
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return filterSQLParams(sql, params)
+}
+
+func filterSQLParams(sql string, params []interface{}) (string, []interface{}) {
+	if getRedactor().hideSQLValues {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// redactGormLogger mask patterns in the messages and sql of a gorm logger before they are written
+type redactGormLogger struct {
+	logger.Interface
+}
+
+// LogMode implement logger.Interface
+func (l redactGormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return redactGormLogger{l.Interface.LogMode(level)}
+}
+
+// Info implement logger.Interface
+func (l redactGormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.Interface.Info(ctx, "%s", getRedactor().text(fmt.Sprintf(msg, data...)))
+}
+
+// Warn implement logger.Interface
+func (l redactGormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.Interface.Warn(ctx, "%s", getRedactor().text(fmt.Sprintf(msg, data...)))
+}
+
+// Error implement logger.Interface
+func (l redactGormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.Interface.Error(ctx, "%s", getRedactor().text(fmt.Sprintf(msg, data...)))
+}
+
+// Trace implement logger.Interface
+func (l redactGormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.Interface.Trace(ctx, begin, func() (string, int64) {
+		sql, rows := fc()
+		return getRedactor().text(sql), rows
+	}, err)
+}
+
+// ParamsFilter implement gorm.ParamsFilter
+func (l redactGormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return filterSQLParams(sql, params)
+}
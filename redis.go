@@ -2,11 +2,12 @@ package frame
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/sirupsen/logrus"
 )
 
 var redisOnce sync.Once
@@ -31,10 +32,26 @@ func newRedisServers(conf *Config) {
 			for _, v := range conf.Redis.Configs {
 				openRedis(v)
 			}
+			if conf.Tracing.Enable {
+				for _, client := range redisMultiConn.clients {
+					client.AddHook(redisTracing{})
+				}
+			}
 		}
 	})
 }
 
+// closeRedisServers close all redis connection pools
+func closeRedisServers() error {
+	var errs []error
+	for name, client := range redisMultiConn.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func openRedis(item RedisConfigItem) {
 	if !item.Enable {
 		return
@@ -52,12 +69,11 @@ func openRedis(item RedisConfigItem) {
 
 // Define a custom logging hook
 type redisLogHook struct {
-	Log     *logrus.Logger
 	Disable bool
 }
 
 func newRedisLogHook(config *Config) redis.Hook {
-	return &redisLogHook{Log: NewLogger(config), Disable: config.Redis.DisableReqLog}
+	return &redisLogHook{Disable: config.Redis.DisableReqLog}
 }
 
 // BeforeProcess logs the command before it is processed
@@ -65,9 +81,7 @@ func (l redisLogHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (conte
 	if l.Disable {
 		return ctx, nil
 	}
-	l.Log.WithFields(logrus.Fields{
-		TraceIDKey: getTraceIDFromContext(ctx),
-	}).Infof("Redis command: %s", cmd.String())
+	traceEntry(LoggerRedis, getTraceIDFromContext(ctx)).Infof("Redis command: %s", getRedactor().redisCmd(cmd))
 	return ctx, nil
 }
 
@@ -83,14 +97,12 @@ func (l redisLogHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cm
 	}
 	cmdstr := []string{}
 	for _, cmd := range cmds {
-		cmdstr = append(cmdstr, cmd.String())
+		cmdstr = append(cmdstr, getRedactor().redisCmd(cmd))
 	}
 	if len(cmds) <= 0 {
 		return ctx, nil
 	}
-	l.Log.WithFields(logrus.Fields{
-		TraceIDKey: getTraceIDFromContext(ctx),
-	}).Infof("Redis pipeline commands: %s", strings.Join(cmdstr, " "))
+	traceEntry(LoggerRedis, getTraceIDFromContext(ctx)).Infof("Redis pipeline commands: %s", strings.Join(cmdstr, " "))
 	return ctx, nil
 }
 
@@ -8,30 +8,29 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// var client = req.C().
-// 	OnAfterResponse(ReqMetricMiddleware)
-
-// ReqMetricMiddleware http req client
-var ReqMetricMiddleware req.ResponseMiddleware = func(c *req.Client, resp *req.Response) error {
+// reqMiddleware count requests sent by the http client
+func (m *metrics) reqMiddleware(c *req.Client, resp *req.Response) error {
 	// TODO: bus code metrics
 	req := resp.Request
 	code := ""
 	if resp.Response != nil {
 		code = strconv.Itoa(resp.Response.StatusCode)
 	}
-	sendHTTPRequests.WithLabelValues(
-		req.Method, req.URL.Host, req.URL.Path, code,
+	host := m.clientHosts.value(req.URL.Host)
+	path := m.clientPaths.value(clientPathLabel(c, req))
+	m.sendHTTPRequests.WithLabelValues(
+		req.Method, host, path, code,
 	).Inc()
-	duration := resp.TotalTime().Milliseconds()
-	sendHTTPRequestsDuration.WithLabelValues(
-		req.Method, req.URL.Host, req.URL.Path, code,
-	).Observe(float64(duration))
+	m.sendHTTPDuration.WithLabelValues(
+		req.Method, host, path, code,
+	).Observe(resp.TotalTime().Seconds())
 	return nil
 }
 
 // ReqLogMiddleware http req client
 var ReqLogMiddleware req.ResponseMiddleware = func(c *req.Client, resp *req.Response) error {
 	logBody := newTraceLogFromHTTPClient(c, resp)
+	getRedactor().logBody(logBody)
 	l := client2logEntry(c)
 	l.WithField(TraceLogKey, logBody).Info("")
 	return nil
@@ -39,7 +38,7 @@ var ReqLogMiddleware req.ResponseMiddleware = func(c *req.Client, resp *req.Resp
 
 func newTraceLogFromHTTPClient(c *req.Client, resp *req.Response) *logBody {
 	cr := resp.Request
-	traceID := c.Headers.Get(TraceIDKey)
+	traceID := c.Headers.Get(getTraceIDConfig().getOutgoingHeader())
 	code := 0
 	if resp.Response != nil {
 		code = resp.Response.StatusCode
@@ -83,6 +82,6 @@ func newTraceLogFromHTTPClient(c *req.Client, resp *req.Response) *logBody {
 }
 
 func client2logEntry(c *req.Client) *logrus.Entry {
-	traceID := c.Headers.Get(TraceIDKey)
-	return NewLogger(getLogConf()).WithField(TraceIDKey, traceID)
+	traceID := c.Headers.Get(getTraceIDConfig().getOutgoingHeader())
+	return traceEntry(LoggerHTTPClient, traceID)
 }
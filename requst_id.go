@@ -1,27 +1,226 @@
 package frame
 
 import (
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// TraceFunc trace id func
+// trace id generators and invalid incoming trace id policies
+const (
+	TraceIDGeneratorProjectUUID = "project_uuid" // base64 project prefix + uuid v4
+	TraceIDGeneratorUUIDv4      = "uuidv4"
+	TraceIDGeneratorUUIDv7      = "uuidv7"
+	TraceIDGeneratorULID        = "ulid"
+	TraceIDGeneratorHex         = "hex" // 128-bit, 32 hex chars
+
+	TraceIDInvalidReplace = "replace"
+	TraceIDInvalidReject  = "reject"
+)
+
+// TraceIDConfig trace id header and generator config
+type TraceIDConfig struct {
+	Header         string   `json:"header"`                                                                // incoming header, default trace_id
+	Aliases        []string `json:"aliases"`                                                               // accepted incoming header aliases, eg: X-Request-ID
+	OutgoingHeader string   `json:"outgoing_header" yaml:"outgoing_header" mapstructure:"outgoing_header"` // response and DoHTTP request header, default header
+	Generator      string   `json:"generator"`                                                             // project_uuid/uuidv4/uuidv7/ulid/hex, default project_uuid
+	InvalidPolicy  string   `json:"invalid_policy" yaml:"invalid_policy" mapstructure:"invalid_policy"`    // replace/reject malformed incoming trace id, default replace
+	MaxLength      int      `json:"max_length" yaml:"max_length" mapstructure:"max_length"`                // default 128
+}
+
+// Validate check trace id config
+func (tc TraceIDConfig) Validate() []error {
+	var errs []error
+	switch tc.Generator {
+	case "", TraceIDGeneratorProjectUUID, TraceIDGeneratorUUIDv4, TraceIDGeneratorUUIDv7, TraceIDGeneratorULID, TraceIDGeneratorHex:
+	default:
+		errs = append(errs, errors.New("please fill in the correct trace_id.generator in the configuration file, choose one of: project_uuid/uuidv4/uuidv7/ulid/hex"))
+	}
+	if !(tc.InvalidPolicy == "" || tc.InvalidPolicy == TraceIDInvalidReplace || tc.InvalidPolicy == TraceIDInvalidReject) {
+		errs = append(errs, errors.New("please fill in the correct trace_id.invalid_policy in the configuration file, choose one of: replace/reject"))
+	}
+	for _, h := range append([]string{tc.Header, tc.OutgoingHeader}, tc.Aliases...) {
+		if h != "" && !headerNameRegexp.MatchString(h) {
+			errs = append(errs, fmt.Errorf("trace_id header name %q is invalid, please reset it", h))
+		}
+	}
+	return errs
+}
+
+func (tc TraceIDConfig) getHeader() string {
+	if tc.Header == "" {
+		return TraceIDKey
+	}
+	return tc.Header
+}
+
+func (tc TraceIDConfig) getOutgoingHeader() string {
+	if tc.OutgoingHeader == "" {
+		return tc.getHeader()
+	}
+	return tc.OutgoingHeader
+}
+
+func (tc TraceIDConfig) getMaxLength() int {
+	if tc.MaxLength <= 0 {
+		return defaultTraceIDMaxLength
+	}
+	return tc.MaxLength
+}
+
+// incomingHeaders header and aliases in lookup order
+func (tc TraceIDConfig) incomingHeaders() []string {
+	return append([]string{tc.getHeader()}, tc.Aliases...)
+}
+
+// valid incoming trace id, only printable id chars are accepted to prevent log injection
+func (tc TraceIDConfig) valid(traceID string) bool {
+	return len(traceID) <= tc.getMaxLength() && traceIDRegexp.MatchString(traceID)
+}
+
+var (
+	headerNameRegexp = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+	traceIDRegexp    = regexp.MustCompile(`^[A-Za-z0-9._:+/=-]+$`)
+)
+
+// TraceIDGenerator generate trace id of requests without a valid incoming trace id
+type TraceIDGenerator interface {
+	Generate() string
+}
+
+// TraceIDGeneratorFunc function adapter of TraceIDGenerator
+type TraceIDGeneratorFunc func() string
+
+// Generate implement TraceIDGenerator
+func (f TraceIDGeneratorFunc) Generate() string {
+	return f()
+}
+
+// built-in trace id generators
+var (
+	UUIDv4Generator TraceIDGenerator = TraceIDGeneratorFunc(uuid.NewString)
+	UUIDv7Generator TraceIDGenerator = TraceIDGeneratorFunc(func() string {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return uuid.NewString()
+		}
+		return id.String()
+	})
+	ULIDGenerator TraceIDGenerator = TraceIDGeneratorFunc(newULID)
+	HexGenerator  TraceIDGenerator = TraceIDGeneratorFunc(func() string {
+		var b [16]byte
+		rand.Read(b[:])
+		return hex.EncodeToString(b[:])
+	})
+)
+
+// traceIDOptions trace id config of the app, shared by http, grpc and http client
+var traceIDOptions = struct {
+	sync.RWMutex
+	conf   TraceIDConfig
+	custom TraceIDGenerator
+}{}
+
+// SetTraceIDGenerator replace the generator of trace_id.generator config, eg: frame.SetTraceIDGenerator(frame.HexGenerator)
+func SetTraceIDGenerator(g TraceIDGenerator) {
+	traceIDOptions.Lock()
+	defer traceIDOptions.Unlock()
+	traceIDOptions.custom = g
+}
+
+func setTraceIDConfig(conf TraceIDConfig) {
+	traceIDOptions.Lock()
+	defer traceIDOptions.Unlock()
+	traceIDOptions.conf = conf
+}
+
+func getTraceIDConfig() TraceIDConfig {
+	traceIDOptions.RLock()
+	defer traceIDOptions.RUnlock()
+	return traceIDOptions.conf
+}
+
+// incomingTraceID first valid trace id of the incoming headers, invalid is true when a malformed one is found
+func incomingTraceID(h http.Header) (traceID string, invalid bool) {
+	conf := getTraceIDConfig()
+	for _, name := range conf.incomingHeaders() {
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+		if !conf.valid(v) {
+			return "", true
+		}
+		return v, false
+	}
+	return "", false
+}
+
+// TraceFunc trace id func, when tracing is enabled a server span is started from incoming traceparent/b3,
+// the trace_id header (or aliases) is still used as frame trace id, otherwise the span trace id is used.
+// Malformed incoming trace id is replaced or rejected by trace_id.invalid_policy.
 func TraceFunc() HandlerFunc {
 	return func(c *Context) {
-		traceID := c.Gtx.Request.Header.Get(TraceIDKey)
+		var span trace.Span
+		if c.config.Tracing.Enable {
+			span = c.startHTTPSpan()
+			defer c.endHTTPSpan(span)
+		}
+		conf := getTraceIDConfig()
+		traceID, invalid := incomingTraceID(c.Gtx.Request.Header)
+		if traceID == "" {
+			traceID = spanTraceID(span)
+		}
 		if traceID == "" {
 			traceID = generateTraceID(c.config.Project)
-			c.Gtx.Request.Header.Set(TraceIDKey, traceID)
 		}
-		c.traceID = traceID
-		c.Gtx.Writer.Header().Set(TraceIDKey, traceID)
+		c.Gtx.Request.Header.Set(conf.getHeader(), traceID)
+		if span != nil {
+			span.SetAttributes(attribute.String(frameTraceIDAttr, traceID))
+		}
+		c.setTraceID(traceID)
+		c.Gtx.Writer.Header().Set(conf.getOutgoingHeader(), traceID)
+		if invalid {
+			if conf.InvalidPolicy == TraceIDInvalidReject {
+				c.HTTPError(http.StatusBadRequest, ErrInvalidParams.WithDetail("invalid trace id"))
+				c.Gtx.Abort()
+				return
+			}
+			c.Warnln("malformed incoming trace id is replaced")
+		}
 		c.Gtx.Next()
 	}
 }
 
+// generateTraceID generate trace id by SetTraceIDGenerator or trace_id.generator config
 func generateTraceID(project ...string) string {
+	traceIDOptions.RLock()
+	custom, name := traceIDOptions.custom, traceIDOptions.conf.Generator
+	traceIDOptions.RUnlock()
+	if custom != nil {
+		return custom.Generate()
+	}
+	switch name {
+	case TraceIDGeneratorUUIDv4:
+		return UUIDv4Generator.Generate()
+	case TraceIDGeneratorUUIDv7:
+		return UUIDv7Generator.Generate()
+	case TraceIDGeneratorULID:
+		return ULIDGenerator.Generate()
+	case TraceIDGeneratorHex:
+		return HexGenerator.Generate()
+	}
 	prefix := ""
 	if len(project) > 0 && project[0] != "" {
 		prefix = base64.StdEncoding.EncodeToString([]byte(project[0]))
@@ -33,3 +232,30 @@ func generateTraceID(project ...string) string {
 	}
 	return traceID
 }
+
+// crockford base32 alphabet of ulid
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID 48-bit ms timestamp + 80-bit random, 26 chars
+func newULID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	rand.Read(b[6:])
+	// 26 * 5 = 130 bits, the first 2 bits are zero
+	out := make([]byte, 26)
+	for i := range out {
+		var v byte
+		for j := 0; j < 5; j++ {
+			k := i*5 + j - 2
+			v <<= 1
+			if k >= 0 && b[k/8]&(0x80>>(k%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out)
+}
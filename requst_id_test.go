@@ -0,0 +1,139 @@
+package frame
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withTraceIDConfig set the trace id config of the test and restore it afterwards
+func withTraceIDConfig(t *testing.T, conf TraceIDConfig) {
+	old := getTraceIDConfig()
+	setTraceIDConfig(conf)
+	t.Cleanup(func() { setTraceIDConfig(old) })
+}
+
+func TestGenerateTraceID(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator string
+		project   string
+		want      *regexp.Regexp
+	}{
+		{name: "project uuid", generator: "", project: "demo", want: regexp.MustCompile(`^ZGVtbw--[0-9a-f-]{36}$`)},
+		{name: "project uuid without project", generator: TraceIDGeneratorProjectUUID, want: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`)},
+		{name: "uuidv4", generator: TraceIDGeneratorUUIDv4, project: "demo", want: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`)},
+		{name: "uuidv7", generator: TraceIDGeneratorUUIDv7, want: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`)},
+		{name: "ulid", generator: TraceIDGeneratorULID, want: regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)},
+		{name: "hex", generator: TraceIDGeneratorHex, want: regexp.MustCompile(`^[0-9a-f]{32}$`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTraceIDConfig(t, TraceIDConfig{Generator: tt.generator})
+			got := generateTraceID(tt.project)
+			if !tt.want.MatchString(got) {
+				t.Errorf("generateTraceID() = %v, want match %v", got, tt.want)
+			}
+			if !getTraceIDConfig().valid(got) {
+				t.Errorf("generateTraceID() = %v is not a valid incoming trace id", got)
+			}
+			if again := generateTraceID(tt.project); again == got {
+				t.Errorf("generateTraceID() returned %v twice", got)
+			}
+		})
+	}
+}
+
+func TestSetTraceIDGenerator(t *testing.T) {
+	withTraceIDConfig(t, TraceIDConfig{Generator: TraceIDGeneratorHex})
+	SetTraceIDGenerator(TraceIDGeneratorFunc(func() string { return "custom" }))
+	t.Cleanup(func() { SetTraceIDGenerator(nil) })
+	if got := generateTraceID("demo"); got != "custom" {
+		t.Errorf("generateTraceID() = %v, want custom", got)
+	}
+}
+
+func TestNewULIDOrder(t *testing.T) {
+	a := newULID()
+	time.Sleep(2 * time.Millisecond)
+	b := newULID()
+	if a >= b {
+		t.Errorf("newULID() = %v then %v, want increasing by time", a, b)
+	}
+}
+
+func TestTraceIDConfigValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    TraceIDConfig
+		traceID string
+		want    bool
+	}{
+		{name: "hex", traceID: "4bf92f3577b34da6a3ce929d0e0e4736", want: true},
+		{name: "project uuid", traceID: "ZGVtbw--0b7c6c1e-2f1a-4c5e-9d7e-6a1b2c3d4e5f", want: true},
+		{name: "base64 chars", traceID: "a+b/c=d:e.f_g", want: true},
+		{name: "empty", traceID: "", want: false},
+		{name: "newline", traceID: "abc\nlevel=error msg=fake", want: false},
+		{name: "space", traceID: "abc def", want: false},
+		{name: "quote", traceID: `abc"}`, want: false},
+		{name: "too long", traceID: strings.Repeat("a", defaultTraceIDMaxLength+1), want: false},
+		{name: "max length", traceID: strings.Repeat("a", defaultTraceIDMaxLength), want: true},
+		{name: "custom max length", conf: TraceIDConfig{MaxLength: 4}, traceID: "abcde", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.conf.valid(tt.traceID); got != tt.want {
+				t.Errorf("valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncomingTraceID(t *testing.T) {
+	tests := []struct {
+		name        string
+		conf        TraceIDConfig
+		header      http.Header
+		wantTraceID string
+		wantInvalid bool
+	}{
+		{name: "default header", header: http.Header{"Trace_id": {"abc"}}, wantTraceID: "abc"},
+		{name: "no header", header: http.Header{}},
+		{name: "alias", conf: TraceIDConfig{Aliases: []string{"X-Request-ID"}}, header: http.Header{"X-Request-Id": {"req-1"}}, wantTraceID: "req-1"},
+		{name: "header before alias", conf: TraceIDConfig{Aliases: []string{"X-Request-ID"}}, header: http.Header{"Trace_id": {"abc"}, "X-Request-Id": {"req-1"}}, wantTraceID: "abc"},
+		{name: "custom header", conf: TraceIDConfig{Header: "X-Trace-ID"}, header: http.Header{"Trace_id": {"abc"}, "X-Trace-Id": {"xyz"}}, wantTraceID: "xyz"},
+		{name: "malformed", header: http.Header{"Trace_id": {"abc\r\n"}}, wantInvalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTraceIDConfig(t, tt.conf)
+			traceID, invalid := incomingTraceID(tt.header)
+			if traceID != tt.wantTraceID || invalid != tt.wantInvalid {
+				t.Errorf("incomingTraceID() = %v, %v, want %v, %v", traceID, invalid, tt.wantTraceID, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestTraceIDConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    TraceIDConfig
+		wantErr bool
+	}{
+		{name: "default", conf: TraceIDConfig{}},
+		{name: "full", conf: TraceIDConfig{Header: "X-Trace-ID", Aliases: []string{"X-Request-ID"}, Generator: TraceIDGeneratorULID, InvalidPolicy: TraceIDInvalidReject}},
+		{name: "unknown generator", conf: TraceIDConfig{Generator: "snowflake"}, wantErr: true},
+		{name: "unknown policy", conf: TraceIDConfig{InvalidPolicy: "ignore"}, wantErr: true},
+		{name: "invalid header", conf: TraceIDConfig{Aliases: []string{"X Request"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := tt.conf.Validate(); (len(errs) > 0) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
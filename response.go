@@ -8,23 +8,25 @@ import (
 
 // Response http response data
 type Response struct {
-	Code    string      `json:"code,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
-	Message string      `json:"message,omitempty"`
-	Time    time.Time   `json:"time,omitempty"`
-	TraceID string      `json:"trace_id,omitempty"`
+	Code    string      `json:"code,omitempty" xml:"code,omitempty"`
+	Data    interface{} `json:"data,omitempty" xml:"data,omitempty"`
+	Message string      `json:"message,omitempty" xml:"message,omitempty"`
+	Time    time.Time   `json:"time,omitempty" xml:"time"`
+	TraceID string      `json:"trace_id,omitempty" xml:"trace_id,omitempty"`
 }
 
 // PageResults http response list data
 type PageResults struct {
-	Total    int         `json:"total,omitempty"`
-	Page     int         `json:"page,omitempty"`
-	PageSize int         `json:"page_size,omitempty"`
-	Results  interface{} `json:"results,omitempty"`
+	Total    int         `json:"total,omitempty" xml:"total,omitempty"`
+	Page     int         `json:"page,omitempty" xml:"page,omitempty"`
+	PageSize int         `json:"page_size,omitempty" xml:"page_size,omitempty"`
+	Results  interface{} `json:"results,omitempty" xml:"results,omitempty"`
+	// NextCursor keyset pagination cursor of the next page, empty means no more
+	NextCursor string `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
 }
 
 // Success http response ok
-// default json
+// default json, encoding is negotiated by Accept: json/xml/msgpack/protobuf
 func (ctx *Context) Success(data interface{}) {
 	resp := &Response{
 		Code:    successCode,
@@ -33,7 +35,7 @@ func (ctx *Context) Success(data interface{}) {
 		Time:    time.Now(),
 		TraceID: ctx.GetTraceID(),
 	}
-	ctx.Gtx.JSON(200, resp)
+	ctx.render(http.StatusOK, resp)
 }
 
 // ErrorMsg frame err msg
@@ -46,45 +48,22 @@ type ErrorMsg interface {
 	GetReply() string
 }
 
-// Error http response error msg
+// Error http response error msg, http status is declared by RegisterCode, default 200,
+// reply language is picked by Accept-Language
 // default json
 func (ctx *Context) Error(errMsg ErrorMsg) {
-	resp := &Response{
-		Code:    errMsg.GetCode(),
-		Message: errMsg.GetReply(),
-		Data:    nil,
-		Time:    time.Now(),
-		TraceID: ctx.GetTraceID(),
-	}
-	ctx.printRealMsgLog(errMsg.GetReal())
-	ctx.Gtx.JSON(http.StatusOK, resp)
+	ctx.renderError(errorStatus(errMsg), errMsg, nil)
 }
 
 // HTTPError http response error msg and setting http code
 // default json
 func (ctx *Context) HTTPError(httpCode int, errMsg ErrorMsg) {
-	resp := &Response{
-		Code:    errMsg.GetCode(),
-		Message: errMsg.GetReply(),
-		Data:    nil,
-		Time:    time.Now(),
-		TraceID: ctx.GetTraceID(),
-	}
-	ctx.printRealMsgLog(errMsg.GetReal())
-	ctx.Gtx.JSON(httpCode, resp)
+	ctx.renderError(httpCode, errMsg, nil)
 }
 
 // HTTPError2 http error response
 func (ctx *Context) HTTPError2(httpCode int, bussCode, userReply string, realMsg error) {
-	resp := &Response{
-		Code:    bussCode,
-		Message: userReply,
-		Data:    nil,
-		Time:    time.Now(),
-		TraceID: ctx.GetTraceID(),
-	}
-	ctx.printRealMsgLog(realMsg.Error())
-	ctx.Gtx.JSON(httpCode, resp)
+	ctx.renderError(httpCode, newErrorMsg(bussCode, userReply, realMsg), nil)
 }
 
 func (ctx *Context) printRealMsgLog(realMsg string) {
@@ -106,22 +85,58 @@ func (ctx *Context) HTTPListSuccess(pageData *PageResults) {
 		Time:    time.Now(),
 		TraceID: ctx.GetTraceID(),
 	}
-	ctx.Gtx.JSON(http.StatusOK, resp)
+	ctx.render(http.StatusOK, resp)
 }
 
 // HTTPListError if pageData nil or pageData.Results id empty,auto set []
 // http response data or date.results is slice or array
 // default json
 func (ctx *Context) HTTPListError(errMsg ErrorMsg) {
+	ctx.renderError(http.StatusOK, errMsg, defaultEmptyPage)
+}
+
+// renderError reply Response envelope or problem details by response mode
+func (ctx *Context) renderError(httpCode int, errMsg ErrorMsg, data interface{}) {
+	ctx.printRealMsgLog(errMsg.GetReal())
+	if ctx.isProblemMode() {
+		ctx.renderProblem(httpCode, errMsg)
+		return
+	}
 	resp := &Response{
 		Code:    errMsg.GetCode(),
-		Message: errMsg.GetReply(),
-		Data:    defaultEmptyPage,
+		Message: ctx.replyOf(errMsg),
+		Data:    data,
 		Time:    time.Now(),
 		TraceID: ctx.GetTraceID(),
 	}
-	ctx.printRealMsgLog(errMsg.GetReal())
-	ctx.Gtx.JSON(http.StatusOK, resp)
+	ctx.render(httpCode, resp)
+}
+
+// errorMsg plain ErrorMsg implement
+type errorMsg struct {
+	code  string
+	reply string
+	real  string
+}
+
+func newErrorMsg(code, reply string, real error) *errorMsg {
+	e := &errorMsg{code: code, reply: reply}
+	if real != nil {
+		e.real = real.Error()
+	}
+	return e
+}
+
+func (e *errorMsg) GetCode() string {
+	return e.code
+}
+
+func (e *errorMsg) GetReal() string {
+	return e.real
+}
+
+func (e *errorMsg) GetReply() string {
+	return e.reply
 }
 
 func realMsgs(msg string) *realMsg {
@@ -148,15 +163,7 @@ func (m *realMsg) String() string {
 // http response data or date.results is slice or array
 // default json
 func (ctx *Context) HTTPListError2(httpCode int, errMsg ErrorMsg) {
-	resp := &Response{
-		Code:    errMsg.GetCode(),
-		Message: errMsg.GetReply(),
-		Data:    defaultEmptyPage,
-		Time:    time.Now(),
-		TraceID: ctx.GetTraceID(),
-	}
-	ctx.printRealMsgLog(errMsg.GetReal())
-	ctx.Gtx.JSON(httpCode, resp)
+	ctx.renderError(httpCode, errMsg, defaultEmptyPage)
 }
 
 func emptyPage(pageData *PageResults) {
@@ -0,0 +1,104 @@
+package frame
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouterGroup frame router group, every handler in the chain share the same *Context
+type RouterGroup struct {
+	group  *gin.RouterGroup
+	app    *App
+	server string
+}
+
+func newRouterGroup(app *App, server string, group *gin.RouterGroup) *RouterGroup {
+	return &RouterGroup{group: group, app: app, server: server}
+}
+
+// BasePath return router group base path, eg: /api/v1
+func (g *RouterGroup) BasePath() string {
+	return g.group.BasePath()
+}
+
+// Use use middleware on this group
+func (g *RouterGroup) Use(middleware ...HandlerFunc) {
+	g.group.Use(g.app.wrapHandlers(middleware)...)
+}
+
+// Group create a nested group, handlers are group middleware
+// eg: v1 := app.Group("/api/v1", AuthFunc())
+func (g *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *RouterGroup {
+	return newRouterGroup(g.app, g.server, g.group.Group(relativePath, g.app.wrapHandlers(handlers)...))
+}
+
+// Handle register handlers with the given http method,
+// the last handler is the real handler, others are route middleware
+func (g *RouterGroup) Handle(httpMethod, relativePath string, handlers ...HandlerFunc) {
+	g.group.Handle(httpMethod, relativePath, g.app.wrapHandlers(handlers)...)
+	g.app.routes.add(g.server, httpMethod, joinPaths(g.BasePath(), relativePath), handlers)
+}
+
+// GET get method
+func (g *RouterGroup) GET(relativePath string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodGet, relativePath, handlers...)
+}
+
+// POST post method
+func (g *RouterGroup) POST(relativePath string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPost, relativePath, handlers...)
+}
+
+// PUT http put method
+func (g *RouterGroup) PUT(relativePath string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPut, relativePath, handlers...)
+}
+
+// PATCH  http patch method
+func (g *RouterGroup) PATCH(relativePath string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPatch, relativePath, handlers...)
+}
+
+// DELETE http delete method
+func (g *RouterGroup) DELETE(relativePath string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodDelete, relativePath, handlers...)
+}
+
+// HEAD http head method
+func (g *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodHead, relativePath, handlers...)
+}
+
+// OPTIONS http options method
+func (g *RouterGroup) OPTIONS(relativePath string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodOptions, relativePath, handlers...)
+}
+
+// Any register handlers for all http methods
+func (g *RouterGroup) Any(relativePath string, handlers ...HandlerFunc) {
+	g.group.Any(relativePath, g.app.wrapHandlers(handlers)...)
+	for _, method := range anyMethods {
+		g.app.routes.add(g.server, method, joinPaths(g.BasePath(), relativePath), handlers)
+	}
+}
+
+// anyMethods http methods registered by Any
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+func joinPaths(absolutePath, relativePath string) string {
+	if relativePath == "" {
+		return absolutePath
+	}
+	finalPath := path.Join(absolutePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		return finalPath + "/"
+	}
+	return finalPath
+}
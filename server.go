@@ -0,0 +1,89 @@
+package frame
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server frame http server, one per http_server.configs item
+type Server struct {
+	*gin.Engine
+	*RouterGroup
+	app  *App
+	name string
+	port string
+}
+
+func newServer(app *App, name, port string) *Server {
+	engine := defaultEngine()
+	engine.Use(app.contextHandler())
+	return &Server{
+		Engine:      engine,
+		RouterGroup: newRouterGroup(app, name, &engine.RouterGroup),
+		app:         app,
+		name:        name,
+		port:        port,
+	}
+}
+
+// Name return server name, http_server.configs[].name
+func (s *Server) Name() string {
+	return s.name
+}
+
+// Port return server listen port, eg: :8080
+func (s *Server) Port() string {
+	return s.port
+}
+
+// Use use middleware only on this server
+func (s *Server) Use(middleware ...HandlerFunc) {
+	s.Engine.Use(s.app.wrapHandlers(middleware)...)
+}
+
+// NoRoute handlers for 404, global middleware are executed first
+func (s *Server) NoRoute(handlers ...HandlerFunc) {
+	s.Engine.NoRoute(s.app.wrapHandlers(handlers)...)
+}
+
+// NoMethod handlers for 405, global middleware are executed first
+func (s *Server) NoMethod(handlers ...HandlerFunc) {
+	s.Engine.HandleMethodNotAllowed = true
+	s.Engine.NoMethod(s.app.wrapHandlers(handlers)...)
+}
+
+// Server return http server by http_server.configs[].name, panic when name not found
+// eg: app.Server("internal").GET("/admin/users", handler)
+func (e *App) Server(name string) *Server {
+	s, ok := e.servers[name]
+	if !ok {
+		panic(fmt.Sprintf("http server %s can't find, please check http_server.configs", name))
+	}
+	return s
+}
+
+// Servers return all business http servers in config order
+func (e *App) Servers() []*Server {
+	list := make([]*Server, 0, len(e.serverNames))
+	for _, name := range e.serverNames {
+		list = append(list, e.servers[name])
+	}
+	return list
+}
+
+// newServers create one server per business http_server.configs item,
+// the first one is the default server used by App.GET/POST/...
+func (e *App) newServers() {
+	e.servers = map[string]*Server{}
+	for _, v := range e.config.HTTPServer.getBusServerConfigs() {
+		e.servers[v.Name] = newServer(e, v.Name, v.Port)
+		e.serverNames = append(e.serverNames, v.Name)
+	}
+	if len(e.serverNames) > 0 {
+		e.defaultServer = e.servers[e.serverNames[0]]
+		return
+	}
+	// http server disabled, routes are registered but never served
+	e.defaultServer = newServer(e, defaultServerName, "")
+}
@@ -0,0 +1,153 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MIMEEventStream server-sent events content type
+const MIMEEventStream = "text/event-stream"
+
+// SSEvent server-sent event
+type SSEvent struct {
+	Event string      // event name, empty means message
+	ID    string      // event id, the client sends it back by Last-Event-ID when reconnecting
+	Retry int         // client reconnect delay ms
+	Data  interface{} // string/[]byte are written as is, others are encoded as json
+}
+
+// SSE write a server-sent event and flush it, return error when the client is gone
+// eg: c.SSE("progress", map[string]int{"done": 3})
+func (c *Context) SSE(event string, data interface{}) error {
+	return c.writeSSE(SSEvent{Event: event, Data: data})
+}
+
+// StreamSSE send events of ch until ch is closed or the client is gone,
+// a heartbeat comment is sent when there is no event in http_server.sse_heartbeat_sec (default 15s).
+// return false when the client disconnected
+func (c *Context) StreamSSE(events <-chan SSEvent) bool {
+	c.startSSE()
+	heartbeat := c.sseHeartbeat()
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	done := c.Gtx.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return false
+		case ev, ok := <-events:
+			if !ok {
+				return true
+			}
+			if err := c.writeSSE(ev); err != nil {
+				return false
+			}
+			ticker.Reset(heartbeat)
+		case <-ticker.C:
+			if err := c.writeStream([]byte(":heartbeat\n\n")); err != nil {
+				return false
+			}
+		}
+	}
+}
+
+// Stream call step until it returns false or the client is gone, the response is flushed after every step.
+// return false when the client disconnected
+// eg: c.Stream(func(w io.Writer) bool { w.Write(chunk); return hasMore })
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	c.streaming = true
+	c.liftTimeout()
+	done := c.Gtx.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return false
+		default:
+		}
+		keep := step(c.Gtx.Writer)
+		c.Gtx.Writer.Flush()
+		if !keep {
+			return true
+		}
+	}
+}
+
+// isStreaming streaming response is not buffered by the access log
+func (c *Context) isStreaming() bool {
+	return c.streaming || strings.HasPrefix(c.Gtx.Writer.Header().Get("Content-Type"), MIMEEventStream)
+}
+
+func (c *Context) sseHeartbeat() time.Duration {
+	if c.config != nil && c.config.HTTPServer.SSEHeartbeatSec > 0 {
+		return time.Duration(c.config.HTTPServer.SSEHeartbeatSec) * time.Second
+	}
+	return defaultSSEHeartbeat
+}
+
+// startSSE write sse headers once
+func (c *Context) startSSE() {
+	c.streaming = true
+	c.liftTimeout()
+	if c.Gtx.Writer.Written() {
+		return
+	}
+	h := c.Gtx.Writer.Header()
+	h.Set("Content-Type", MIMEEventStream+"; charset=utf-8")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// disable nginx proxy buffering
+	h.Set("X-Accel-Buffering", "no")
+	c.Gtx.Writer.WriteHeaderNow()
+}
+
+func (c *Context) writeSSE(ev SSEvent) error {
+	c.startSSE()
+	var data string
+	switch v := ev.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	var buf bytes.Buffer
+	if ev.ID != "" {
+		buf.WriteString("id: " + sseEscape(ev.ID) + "\n")
+	}
+	if ev.Event != "" {
+		buf.WriteString("event: " + sseEscape(ev.Event) + "\n")
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: " + strconv.Itoa(ev.Retry) + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	buf.WriteString("\n")
+	return c.writeStream(buf.Bytes())
+}
+
+func (c *Context) writeStream(b []byte) error {
+	if err := c.Gtx.Request.Context().Err(); err != nil {
+		return err
+	}
+	if _, err := c.Gtx.Writer.Write(b); err != nil {
+		return err
+	}
+	c.Gtx.Writer.Flush()
+	return nil
+}
+
+// sseEscape id/event can't contain line breaks
+func sseEscape(s string) string {
+	return strings.NewReplacer("\n", "", "\r", "").Replace(s)
+}
@@ -0,0 +1,109 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TimeoutFunc request timeout middleware, GetDB/GetRedis/DoHTTP calls are cancelled when timeout,
+// reply the timeout response if handler didn't write anything.
+// Use it as route middleware, eg: app.GET("/report", frame.TimeoutFunc(3*time.Second), Report),
+// a route timeout can only be shorter than http_server.request_timeout_sec.
+// Websocket upgrades and SSE requests are not limited, the timeout is lifted once Stream/SSE starts.
+func TimeoutFunc(timeout time.Duration) HandlerFunc {
+	return func(c *Context) {
+		if timeout <= 0 || isLongLived(c.Gtx.Request) {
+			c.Gtx.Next()
+			return
+		}
+		ctx := newRequestDeadline(c.Gtx.Request.Context(), timeout)
+		defer ctx.cancel()
+		c.deadlines = append(c.deadlines, ctx)
+		c.Gtx.Request = c.Gtx.Request.WithContext(ctx)
+		c.Gtx.Next()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Gtx.Writer.Written() {
+			c.Error(ErrTimeout.Wrap(fmt.Errorf("request timeout after %s", timeout)))
+			c.Gtx.Abort()
+		}
+	}
+}
+
+// isLongLived websocket upgrade or SSE request
+func isLongLived(r *http.Request) bool {
+	if websocket.IsWebSocketUpgrade(r) {
+		return true
+	}
+	for _, mime := range parseAccept(r.Header.Get("Accept")) {
+		if mime == MIMEEventStream {
+			return true
+		}
+	}
+	return false
+}
+
+// liftTimeout stop the deadlines of TimeoutFunc, called when the response starts streaming
+func (c *Context) liftTimeout() {
+	for _, d := range c.deadlines {
+		d.lift()
+	}
+}
+
+// requestDeadline request context with a deadline that can be lifted before it is exceeded
+type requestDeadline struct {
+	context.Context
+	cancel   context.CancelFunc
+	timer    *time.Timer
+	mu       sync.Mutex
+	deadline time.Time // zero when lifted
+	err      error
+}
+
+func newRequestDeadline(parent context.Context, timeout time.Duration) *requestDeadline {
+	ctx, cancel := context.WithCancel(parent)
+	d := &requestDeadline{Context: ctx, cancel: cancel, deadline: time.Now().Add(timeout)}
+	d.timer = time.AfterFunc(timeout, func() {
+		d.mu.Lock()
+		if ctx.Err() == nil {
+			d.err = context.DeadlineExceeded
+		}
+		d.mu.Unlock()
+		cancel()
+	})
+	return d
+}
+
+// Deadline the earlier one of the parent and the request deadline
+func (d *requestDeadline) Deadline() (time.Time, bool) {
+	d.mu.Lock()
+	deadline := d.deadline
+	d.mu.Unlock()
+	parent, ok := d.Context.Deadline()
+	if deadline.IsZero() || (ok && parent.Before(deadline)) {
+		return parent, ok
+	}
+	return deadline, true
+}
+
+func (d *requestDeadline) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	return d.Context.Err()
+}
+
+// lift stop the timer, no-op when the deadline is already exceeded
+func (d *requestDeadline) lift() {
+	if d.timer.Stop() {
+		d.mu.Lock()
+		d.deadline = time.Time{}
+		d.mu.Unlock()
+	}
+}
@@ -0,0 +1,167 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestEngine gin engine serving GET /t with the frame handlers
+func newTestEngine(handlers ...HandlerFunc) *gin.Engine {
+	e := &App{config: &Config{}}
+	e.config.HTTPClient.DisableReqLog = true
+	r := gin.New()
+	r.GET("/t", e.wrapHandlers(handlers)...)
+	return r
+}
+
+// replyDeadline reply 204 without deadline, 200 with it
+func replyDeadline(c *Context) {
+	if _, ok := c.Deadline(); ok {
+		c.Gtx.Status(http.StatusOK)
+		return
+	}
+	c.Gtx.Status(http.StatusNoContent)
+}
+
+func TestIsLongLived(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   bool
+	}{
+		{name: "plain", header: http.Header{"Accept": {"application/json"}}, want: false},
+		{name: "websocket upgrade", header: http.Header{"Connection": {"keep-alive, Upgrade"}, "Upgrade": {"websocket"}}, want: true},
+		{name: "other upgrade", header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"h2c"}}, want: false},
+		{name: "sse", header: http.Header{"Accept": {"text/event-stream"}}, want: true},
+		{name: "sse refused", header: http.Header{"Accept": {"text/event-stream;q=0, application/json"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/t", nil)
+			r.Header = tt.header
+			if got := isLongLived(r); got != tt.want {
+				t.Errorf("isLongLived() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeoutFunc(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	tests := []struct {
+		name       string
+		header     http.Header
+		handler    HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "timeout reply",
+			handler:    func(c *Context) { <-c.Done() },
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "fast handler",
+			handler:    func(c *Context) { c.Gtx.String(http.StatusOK, "ok") },
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+		{
+			name:       "websocket upgrade not limited",
+			header:     http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"}},
+			handler:    replyDeadline,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "sse not limited",
+			header:     http.Header{"Accept": {"text/event-stream"}},
+			handler:    replyDeadline,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "stream lifts the timeout",
+			handler: func(c *Context) {
+				c.Stream(func(w io.Writer) bool {
+					time.Sleep(3 * timeout)
+					if c.Err() != nil {
+						io.WriteString(w, "cancelled")
+						return false
+					}
+					if _, ok := c.Deadline(); ok {
+						io.WriteString(w, "deadline")
+						return false
+					}
+					io.WriteString(w, "done")
+					return false
+				})
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "done",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/t", nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			newTestEngine(TimeoutFunc(time.Minute), TimeoutFunc(timeout), tt.handler).ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %v, want %v", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRequestDeadlineLift(t *testing.T) {
+	d := newRequestDeadline(httptest.NewRequest(http.MethodGet, "/", nil).Context(), 20*time.Millisecond)
+	defer d.cancel()
+	<-d.Done()
+	d.lift()
+	if !errors.Is(d.Err(), context.DeadlineExceeded) {
+		t.Errorf("Err() = %v, want deadline exceeded after lifting too late", d.Err())
+	}
+}
+
+func TestDoHTTPRouteTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	handler := func(c *Context) {
+		_, err := c.DoHTTP().R().Get(slow.URL)
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.Gtx.String(http.StatusOK, "cancelled")
+			return
+		}
+		c.Gtx.String(http.StatusOK, "%v", err)
+	}
+	// the client is created before the route timeout starts
+	createClient := func(c *Context) {
+		c.DoHTTP()
+		c.Gtx.Next()
+	}
+	w := httptest.NewRecorder()
+	start := time.Now()
+	newTestEngine(createClient, TimeoutFunc(50*time.Millisecond), handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t", nil))
+	if got := w.Body.String(); got != "cancelled" {
+		t.Errorf("body = %v, want cancelled", got)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %v, want cancelled by the route timeout", elapsed)
+	}
+}
@@ -3,7 +3,10 @@ package frame
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"mime"
 	"net/http"
 	"path"
 	"runtime"
@@ -38,7 +41,14 @@ func NewLogger(conf ...*Config) *logrus.Logger {
 		l = conf[0].LogLevel
 		m = conf[0].LogMode
 	}
-	return newLoggerLevel(l, m)
+	logger := newLoggerLevel(l, m)
+	if len(conf) > 0 && conf[0].Log.Backend == LogBackendSlog {
+		logger.SetFormatter(newSlogFormatter(m))
+	}
+	if w, hooks, ok := logOutput(LogCategoryApp); ok {
+		setLogOutput(logger, w, hooks)
+	}
+	return logger
 }
 
 func newLoggerLevel(level, mode string) *logrus.Logger {
@@ -78,30 +88,41 @@ func log2Level(l string) logrus.Level {
 
 // isFileUpload 判断是否是文件上传接口
 func isFileUpload(r *http.Request) bool {
-	contentType := r.Header.Get("Content-Type")
-	return contentType == "multipart/form-data"
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// peekBody read at most limit bytes of the request body for the log, handlers still read the whole body
+func peekBody(r *http.Request, limit int) string {
+	buf, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read request body")
+	}
+	// reset body
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(buf), r.Body), Closer: r.Body}
+	if len(buf) > limit {
+		return string(buf[:limit]) + truncatedMarker
+	}
+	return string(buf)
 }
 
-// LoggerFunc log func
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// LoggerFunc log func, bodies are truncated by access_log.max_req_body_bytes/max_resp_body_bytes,
+// success logs are sampled by access_log.success_sample_ratio and routes can opt out by access_log.routes
 func LoggerFunc() HandlerFunc {
 	return func(c *Context) {
 		// request start
 		startTime := time.Now()
+		alc := c.config.AccessLog
+		route := alc.route(c.Gtx.Request.Method, c.Gtx.FullPath())
 		//  request body
 		var requestBody string
-		if isFileUpload(c.Gtx.Request) {
-			requestBody = ""
-		} else {
-			if c.Gtx.Request.Body != nil {
-				bodyBytes, err := ioutil.ReadAll(c.Gtx.Request.Body)
-				if err != nil {
-					logrus.WithError(err).Error("Failed to read request body")
-				} else {
-					requestBody = string(bodyBytes)
-				}
-				// reset body
-				c.Gtx.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
-			}
+		if !route.Disable && !route.DisableReqBody && !isFileUpload(c.Gtx.Request) && c.Gtx.Request.Body != nil {
+			requestBody = peekBody(c.Gtx.Request, alc.getMaxReqBodyBytes())
 		}
 
 		// request header
@@ -110,57 +131,94 @@ func LoggerFunc() HandlerFunc {
 		// 	requestHeader[k] = strings.Join(v, ",")
 		// }
 
-		// response body
-		w := &responseWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Gtx.Writer}
-		c.Gtx.Writer = w
+		// response body, not buffered when it is never logged,
+		// then the business code of replies not written by response helpers is unknown
+		var body *bytes.Buffer
+		if !route.Disable && !route.DisableRespBody {
+			body = bytes.NewBufferString("")
+			c.Gtx.Writer = &responseWriter{body: body, ResponseWriter: c.Gtx.Writer, ctx: c, limit: alc.getMaxRespBodyBytes()}
+		}
 
 		// detail request
 		c.Gtx.Next()
 
 		// request end
 		endTime := time.Now()
-		duration := endTime.Sub(startTime).Milliseconds()
-		// log body
-		rb := w.body.String()
-		if !isJSONBody(w) {
+		elapsed := endTime.Sub(startTime)
+		duration := elapsed.Milliseconds()
+		// business code from the response object, non-response-helper replies fallback to parse json body
+		replied, busCode, msg := c.replied, c.replyCode, c.replyMsg
+		isJSON := isJSONBody(c.Gtx.Writer)
+		// streaming response only log duration, status and bytes sent
+		streaming := c.isStreaming()
+		if !replied && !isJSON && !streaming {
 			return
 		}
-		go func() {
-			httpCode := c.Gtx.Writer.Status()
+		// log body, only text encodings
+		var rb string
+		if body != nil && !streaming && (isJSON || isTextBody(c.Gtx.Writer)) {
+			rb = body.String()
+		}
+		size := c.Gtx.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		if rb != "" && size > body.Len() {
+			rb += truncatedMarker
+		}
+		httpCode := c.Gtx.Writer.Status()
+		method := c.Gtx.Request.Method
+		if !replied {
+			busCode = jsonGet(rb, codeKey)
+			msg = jsonGet(rb, msgKey)
+		}
+		// metrics are recorded inline, entries dropped by the access log queue are still counted
+		if c.config.EnableMetric {
+			// route template instead of the request path, so ids in the path do not add series
+			route := c.metrics.routes.value(routeLabel(c.Gtx.FullPath()))
 			hcr := fmt.Sprintf("%d", httpCode)
-			busCode := jsonGet(rb, codeKey)
-			method := c.Gtx.Request.Method
-			url := c.Gtx.Request.URL.Path
-
-			if c.config.EnableMetric {
-				// metrics
-				prometheusRequestDuration.WithLabelValues(url, hcr, method).Observe(float64(duration))
-				prometheusRequestBusCounter.WithLabelValues(url, busCode, method).Inc()
-			}
-			if c.config.HTTPServer.DisableReqLog {
-				return
-			}
+			c.metrics.requestDuration.WithLabelValues(route, hcr, method).Observe(elapsed.Seconds())
+			c.metrics.requestBusCounter.WithLabelValues(route, busCode, method).Inc()
+		}
+		if c.config.HTTPServer.DisableReqLog || route.Disable || c.skipAccessLog {
+			return
+		}
+		// errors are always logged
+		success := httpCode < http.StatusBadRequest && (busCode == "" || busCode == successCode)
+		if success && rand.Float64() >= alc.getSuccessSampleRatio() {
+			return
+		}
+		// snapshot request data, the context is recycled once the request is finished
+		url := c.Gtx.Request.URL.Path
+		query := c.Gtx.Request.URL.Query()
+		params := append(gin.Params(nil), c.Gtx.Params...)
+		traceID := c.GetTraceID()
+		entry := accessEntry(c.Entry)
+		c.submitAccessLog(func() {
 			reqLog := logBody{
 				TraceType:  TraceLogRouter,
-				TraceID:    c.Gtx.Request.Header.Get(TraceIDKey),
+				TraceID:    traceID,
 				Code:       busCode,
-				StatusCode: c.Gtx.Writer.Status(),
+				StatusCode: httpCode,
 				Duration:   duration,
-				Msg:        jsonGet(rb, msgKey),
-				Path:       c.Gtx.Request.URL.Path,
+				Size:       size,
+				Stream:     streaming,
+				Msg:        msg,
+				Path:       url,
 				Extra: reqLogExtra{
 					Req: reqLogBody{
-						QueryParams: c.Gtx.Request.URL.Query(),
-						PathParams:  c.Gtx.Params,
+						QueryParams: query,
+						PathParams:  params,
 						Body:        requestBody,
 					},
 					Resp: respLogBody{
-						Body: w.body.String(),
+						Body: rb,
 					},
 				},
 			}
-			c.WithField(TraceLogKey, reqLog).Info("")
-		}()
+			getRedactor().logBody(&reqLog)
+			entry.WithField(TraceLogKey, reqLog).Info("")
+		})
 	}
 }
 
@@ -170,16 +228,29 @@ func jsonGet(data string, key string) string {
 
 func isJSONBody(w gin.ResponseWriter) bool {
 	t := w.Header().Get("Content-Type")
-	return strings.Contains(t, "application/json")
+	return strings.Contains(t, "application/json") || strings.Contains(t, MIMEProblemJSON)
+}
+
+func isTextBody(w gin.ResponseWriter) bool {
+	t := w.Header().Get("Content-Type")
+	return strings.Contains(t, MIMEXML) || strings.Contains(t, MIMEXML2)
 }
 
 type responseWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body  *bytes.Buffer
+	ctx   *Context
+	limit int // max buffered bytes
 }
 
 func (w responseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	// streaming response is not buffered
+	if n := w.limit - w.body.Len(); n > 0 && !w.ctx.isStreaming() {
+		if len(b) < n {
+			n = len(b)
+		}
+		w.body.Write(b[:n])
+	}
 	return w.ResponseWriter.Write(b)
 }
 
@@ -189,6 +260,8 @@ type logBody struct {
 	Code       string       `json:"code,omitempty"`
 	StatusCode int          `json:"status_code,omitempty"`
 	Duration   int64        `json:"duration,omitempty"` // ms
+	Size       int          `json:"size,omitempty"`     // response bytes
+	Stream     bool         `json:"stream,omitempty"`
 	Msg        string       `json:"msg,omitempty"`
 	Host       string       `json:"host,omitempty"`
 	Path       string       `json:"path,omitempty"`
@@ -0,0 +1,396 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/imroc/req/v3"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"gorm.io/gorm"
+)
+
+// tracing protocols and propagators
+const (
+	TracingProtocolGRPC = "grpc"
+	TracingProtocolHTTP = "http"
+
+	PropagatorTraceContext = "tracecontext"
+	PropagatorBaggage      = "baggage"
+	PropagatorB3           = "b3"      // b3 single header
+	PropagatorB3Multi      = "b3multi" // X-B3-* headers
+	tracerName             = "github.com/normastars/frame"
+	frameTraceIDAttr       = "frame.trace_id"
+	gormSpanKey            = "frame:span"
+	gormTracingPluginName  = "frame:tracing"
+)
+
+// TracingConfig opentelemetry tracing config, spans are exported by OTLP
+type TracingConfig struct {
+	Enable      bool     `json:"enable"`
+	Endpoint    string   `json:"endpoint"`                                                     // collector endpoint, default localhost:4317 (grpc) / localhost:4318 (http)
+	Protocol    string   `json:"protocol"`                                                     // grpc/http, default grpc
+	Insecure    bool     `json:"insecure"`                                                     // disable tls, eg: local collector
+	SampleRatio float64  `json:"sample_ratio" yaml:"sample_ratio" mapstructure:"sample_ratio"` // 0 ~ 1, default 1, parent sampled decision is respected
+	Propagators []string `json:"propagators"`                                                  // tracecontext/baggage/b3/b3multi, default tracecontext,baggage
+}
+
+// Validate check tracing config
+func (tc TracingConfig) Validate() []error {
+	if !tc.Enable {
+		return nil
+	}
+	var errs []error
+	if !(tc.Protocol == "" || tc.Protocol == TracingProtocolGRPC || tc.Protocol == TracingProtocolHTTP) {
+		errs = append(errs, errors.New("please fill in the correct tracing.protocol in the configuration file, choose one of: grpc/http"))
+	}
+	if tc.SampleRatio < 0 || tc.SampleRatio > 1 {
+		errs = append(errs, errors.New("please fill in the correct tracing.sample_ratio in the configuration file, range 0 ~ 1"))
+	}
+	for _, p := range tc.Propagators {
+		switch strings.ToLower(p) {
+		case PropagatorTraceContext, PropagatorBaggage, PropagatorB3, PropagatorB3Multi:
+		default:
+			errs = append(errs, fmt.Errorf("unknown tracing propagator %s, choose from: tracecontext/baggage/b3/b3multi", p))
+		}
+	}
+	return errs
+}
+
+func (tc TracingConfig) getSampleRatio() float64 {
+	if tc.SampleRatio <= 0 {
+		return 1
+	}
+	return tc.SampleRatio
+}
+
+func (tc TracingConfig) propagator() propagation.TextMapPropagator {
+	names := tc.Propagators
+	if len(names) == 0 {
+		names = []string{PropagatorTraceContext, PropagatorBaggage}
+	}
+	var list []propagation.TextMapPropagator
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case PropagatorTraceContext:
+			list = append(list, propagation.TraceContext{})
+		case PropagatorBaggage:
+			list = append(list, propagation.Baggage{})
+		case PropagatorB3:
+			list = append(list, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			list = append(list, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(list...)
+}
+
+var (
+	tracingOnce    sync.Once
+	tracerProvider *sdktrace.TracerProvider
+)
+
+// newTracing set the global tracer provider and propagator, tracing is disabled when the exporter can't be created
+func newTracing(conf *Config) {
+	tracingOnce.Do(func() {
+		if !conf.Tracing.Enable {
+			return
+		}
+		exporter, err := newTracingExporter(conf.Tracing)
+		if err != nil {
+			logrus.Errorf("create tracing exporter failed, tracing is disabled: %v\n", err)
+			return
+		}
+		res := resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(conf.Project),
+			semconv.DeploymentEnvironment(conf.Env),
+		)
+		tracerProvider = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.Tracing.getSampleRatio()))),
+		)
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(conf.Tracing.propagator())
+	})
+}
+
+func newTracingExporter(tc TracingConfig) (*otlptrace.Exporter, error) {
+	if tc.Protocol == TracingProtocolHTTP {
+		opts := []otlptracehttp.Option{}
+		if tc.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(tc.Endpoint))
+		}
+		if tc.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	}
+	opts := []otlptracegrpc.Option{}
+	if tc.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(tc.Endpoint))
+	}
+	if tc.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(context.Background(), opts...)
+}
+
+// shutdownTracing flush and stop exporting spans
+func shutdownTracing(ctx context.Context) error {
+	if tracerProvider == nil {
+		return nil
+	}
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+	return nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// startHTTPSpan extract incoming traceparent/b3 and start the server span of the request
+func (c *Context) startHTTPSpan() trace.Span {
+	r := c.Gtx.Request
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	name := r.Method
+	if route := c.Gtx.FullPath(); route != "" {
+		name += " " + route
+	}
+	ctx, span := tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(r.Method),
+			semconv.HTTPRoute(c.Gtx.FullPath()),
+			semconv.URLPath(getRedactor().text(r.URL.Path)),
+		),
+	)
+	c.Gtx.Request = r.WithContext(ctx)
+	return span
+}
+
+// endHTTPSpan 5xx are marked as error
+func (c *Context) endHTTPSpan(span trace.Span) {
+	code := c.Gtx.Writer.Status()
+	span.SetAttributes(semconv.HTTPStatusCode(code))
+	if code >= http.StatusInternalServerError {
+		span.SetStatus(otelcodes.Error, http.StatusText(code))
+	}
+	span.End()
+}
+
+// grpcMetadataCarrier propagation carrier of grpc metadata
+type grpcMetadataCarrier metadata.MD
+
+func (m grpcMetadataCarrier) Get(key string) string {
+	if v := metadata.MD(m).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (m grpcMetadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m grpcMetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// startGRPCSpan extract incoming traceparent/b3 from metadata and start the server span of the call
+func startGRPCSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, grpcMetadataCarrier(md.Copy()))
+	service, name := method, method
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		service, name = strings.TrimPrefix(method[:i], "/"), method[i+1:]
+	}
+	return tracer().Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(name),
+		),
+	)
+}
+
+// spanTraceID use the otel trace id as frame trace id when the legacy trace_id header is missing
+func spanTraceID(span trace.Span) string {
+	if span == nil || !span.SpanContext().HasTraceID() {
+		return ""
+	}
+	return span.SpanContext().TraceID().String()
+}
+
+// spanError record err with patterns masked in the message, eg: values in duplicate entry errors
+func spanError(span trace.Span, err error) {
+	msg := getRedactor().text(err.Error())
+	if msg != err.Error() {
+		err = errors.New(msg)
+	}
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, msg)
+}
+
+// tracingRoundTrip client span of DoHTTP, the span context is injected into the outgoing headers
+func tracingRoundTrip(rt req.RoundTripper) req.RoundTripFunc {
+	return func(r *req.Request) (*req.Response, error) {
+		method := r.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		url := r.RawURL
+		if r.URL != nil {
+			url = r.URL.String()
+		}
+		ctx, span := tracer().Start(r.Context(), "HTTP "+method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.HTTPMethod(method), semconv.URLFull(getRedactor().url(url))),
+		)
+		defer span.End()
+		if r.Headers == nil {
+			r.Headers = http.Header{}
+		}
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Headers))
+		resp, err := rt.RoundTrip(r)
+		if err != nil {
+			spanError(span, err)
+			return resp, err
+		}
+		if resp != nil && resp.Response != nil {
+			span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+			if resp.StatusCode >= http.StatusBadRequest {
+				span.SetStatus(otelcodes.Error, http.StatusText(resp.StatusCode))
+			}
+		}
+		return resp, err
+	}
+}
+
+// gormTracing gorm plugin, one client span per sql
+type gormTracing struct{}
+
+func (gormTracing) Name() string {
+	return gormTracingPluginName
+}
+
+func (p gormTracing) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("frame:tracing_before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register("frame:tracing_after_create", p.after),
+		cb.Query().Before("gorm:query").Register("frame:tracing_before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register("frame:tracing_after_query", p.after),
+		cb.Update().Before("gorm:update").Register("frame:tracing_before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("frame:tracing_after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("frame:tracing_before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("frame:tracing_after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("frame:tracing_before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("frame:tracing_after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("frame:tracing_before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("frame:tracing_after_raw", p.after),
+	)
+}
+
+func (gormTracing) before(op string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		_, span := tracer().Start(ctx, "gorm."+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperation(op)),
+		)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func (gormTracing) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	// sql without vars, values are not exported, patterns are masked in values written into the sql
+	span.SetAttributes(
+		semconv.DBStatement(getRedactor().text(db.Statement.SQL.String())),
+		semconv.DBSQLTable(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		spanError(span, db.Error)
+	}
+	span.End()
+}
+
+// redisTracing redis hook, one client span per command or pipeline, only command names are exported
+type redisTracing struct{}
+
+func (redisTracing) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = tracer().Start(ctx, "redis."+cmd.Name(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperation(cmd.Name())),
+	)
+	return ctx, nil
+}
+
+func (redisTracing) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	span := trace.SpanFromContext(ctx)
+	if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+		spanError(span, err)
+	}
+	span.End()
+	return nil
+}
+
+func (redisTracing) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+	}
+	ctx, _ = tracer().Start(ctx, "redis.pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperation(strings.Join(names, " "))),
+	)
+	return ctx, nil
+}
+
+func (redisTracing) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	span := trace.SpanFromContext(ctx)
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+			spanError(span, err)
+			break
+		}
+	}
+	span.End()
+	return nil
+}
@@ -0,0 +1,96 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/imroc/req/v3"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+// withSpanRecorder record the spans of the test
+func withSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	old := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(old) })
+	return sr
+}
+
+func TestSpanRedaction(t *testing.T) {
+	withRedactConfig(t, RedactConfig{QueryParams: []string{"token"}, Patterns: []string{`\d{11}`}})
+	tests := []struct {
+		name       string
+		span       func()
+		wantAttrs  map[string]string
+		wantStatus string
+	}{
+		{
+			name: "http client url and error",
+			span: func() {
+				rt := tracingRoundTrip(req.RoundTripFunc(func(r *req.Request) (*req.Response, error) {
+					return nil, errors.New("dial 13812345678 failed")
+				}))
+				_, _ = rt(req.C().R().SetURL("http://a.com/p?token=t&page=1"))
+			},
+			wantAttrs:  map[string]string{"url.full": "http://a.com/p?token=***&page=1"},
+			wantStatus: "dial *** failed",
+		},
+		{
+			name: "sql statement and error",
+			span: func() {
+				db := &gorm.DB{Config: &gorm.Config{}, Statement: &gorm.Statement{Context: context.Background(), Table: "users"}}
+				db.Statement.DB = db
+				gormTracing{}.before("raw")(db)
+				db.Statement.SQL.WriteString("SELECT * FROM users WHERE phone = '13812345678'")
+				db.Error = errors.New("Duplicate entry '13812345678' for key 'phone'")
+				gormTracing{}.after(db)
+			},
+			wantAttrs:  map[string]string{"db.statement": "SELECT * FROM users WHERE phone = '***'", "db.sql.table": "users"},
+			wantStatus: "Duplicate entry '***' for key 'phone'",
+		},
+		{
+			name: "redis command names only",
+			span: func() {
+				cmd := redis.NewStatusCmd(context.Background(), "auth", "s3cret")
+				ctx, _ := redisTracing{}.BeforeProcess(context.Background(), cmd)
+				_ = redisTracing{}.AfterProcess(ctx, cmd)
+			},
+			wantAttrs: map[string]string{"db.operation": "auth"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := withSpanRecorder(t)
+			tt.span()
+			spans := sr.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			attrs := map[string]string{}
+			for _, kv := range spans[0].Attributes() {
+				attrs[string(kv.Key)] = kv.Value.Emit()
+			}
+			for k, want := range tt.wantAttrs {
+				if attrs[k] != want {
+					t.Errorf("%s = %v, want %v", k, attrs[k], want)
+				}
+			}
+			if got := spans[0].Status().Description; got != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got, tt.wantStatus)
+			}
+			for _, ev := range spans[0].Events() {
+				for _, kv := range ev.Attributes {
+					if kv.Key == "exception.message" && kv.Value.AsString() != tt.wantStatus {
+						t.Errorf("exception.message = %v, want %v", kv.Value.AsString(), tt.wantStatus)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WSHandler websocket handler, the connection is closed when it returns
+type WSHandler func(conn *WSConn)
+
+// WebSocketConfig websocket config
+type WebSocketConfig struct {
+	PingIntervalSec int   `json:"ping_interval_sec" yaml:"ping_interval_sec" mapstructure:"ping_interval_sec"` // default 30s
+	PongWaitSec     int   `json:"pong_wait_sec" yaml:"pong_wait_sec" mapstructure:"pong_wait_sec"`             // default 60s, must be longer than ping interval
+	WriteTimeoutSec int   `json:"write_timeout_sec" yaml:"write_timeout_sec" mapstructure:"write_timeout_sec"` // default 10s
+	MaxMessageSize  int64 `json:"max_message_size" yaml:"max_message_size" mapstructure:"max_message_size"`    // bytes, default 1MB
+	AllowAllOrigins bool  `json:"allow_all_origins" yaml:"allow_all_origins" mapstructure:"allow_all_origins"` // default same origin only
+}
+
+func (wc WebSocketConfig) getPingInterval() time.Duration {
+	if wc.PingIntervalSec <= 0 {
+		return defaultWSPingInterval
+	}
+	return time.Duration(wc.PingIntervalSec) * time.Second
+}
+
+func (wc WebSocketConfig) getPongWait() time.Duration {
+	if wc.PongWaitSec <= 0 {
+		return defaultWSPongWait
+	}
+	return time.Duration(wc.PongWaitSec) * time.Second
+}
+
+func (wc WebSocketConfig) getWriteTimeout() time.Duration {
+	if wc.WriteTimeoutSec <= 0 {
+		return defaultWSWriteTimeout
+	}
+	return time.Duration(wc.WriteTimeoutSec) * time.Second
+}
+
+func (wc WebSocketConfig) getMaxMessageSize() int64 {
+	if wc.MaxMessageSize <= 0 {
+		return defaultWSMaxMessageSize
+	}
+	return wc.MaxMessageSize
+}
+
+// Validate check websocket config
+func (wc WebSocketConfig) Validate() []error {
+	if wc.getPongWait() <= wc.getPingInterval() {
+		return []error{errors.New("http_server.websocket.pong_wait_sec must be longer than ping_interval_sec, please reset it")}
+	}
+	return nil
+}
+
+// WSConn websocket connection, Ctx is the frame context of the upgrade request (logger with trace_id, GetDB, GetRedis).
+// Read methods must be called from one goroutine, write methods are safe for concurrent use.
+type WSConn struct {
+	Ctx *Context
+
+	conn      *websocket.Conn
+	path      string
+	conf      WebSocketConfig
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	done      chan struct{}
+	in        int64
+	out       int64
+}
+
+// Conn underlying gorilla websocket connection
+func (c *WSConn) Conn() *websocket.Conn {
+	return c.conn
+}
+
+// ReadMessage read a message, messageType is websocket.TextMessage or websocket.BinaryMessage
+func (c *WSConn) ReadMessage() (messageType int, p []byte, err error) {
+	messageType, p, err = c.conn.ReadMessage()
+	if err == nil {
+		c.countMessage(&c.in, wsDirectionIn)
+	}
+	return messageType, p, err
+}
+
+// ReadJSON read a json message into v
+func (c *WSConn) ReadJSON(v interface{}) error {
+	if err := c.conn.ReadJSON(v); err != nil {
+		return err
+	}
+	c.countMessage(&c.in, wsDirectionIn)
+	return nil
+}
+
+// WriteMessage write a message, messageType is websocket.TextMessage or websocket.BinaryMessage
+func (c *WSConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.conf.getWriteTimeout()))
+	if err := c.conn.WriteMessage(messageType, data); err != nil {
+		return err
+	}
+	c.countMessage(&c.out, wsDirectionOut)
+	return nil
+}
+
+// WriteJSON write v as a json text message
+func (c *WSConn) WriteJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.conf.getWriteTimeout()))
+	if err := c.conn.WriteJSON(v); err != nil {
+		return err
+	}
+	c.countMessage(&c.out, wsDirectionOut)
+	return nil
+}
+
+// Close send close frame with code and text, then close the connection
+// eg: conn.Close(websocket.CloseNormalClosure, "bye")
+func (c *WSConn) Close(code int, text string) error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(c.conf.getWriteTimeout()))
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// Done closed when the connection is closed
+func (c *WSConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// ReadWSJSON read a json message as T
+// eg: msg, err := frame.ReadWSJSON[ChatMessage](conn)
+func ReadWSJSON[T any](conn *WSConn) (*T, error) {
+	v := new(T)
+	if err := conn.ReadJSON(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (c *WSConn) countMessage(n *int64, direction string) {
+	atomic.AddInt64(n, 1)
+	if c.Ctx.config.EnableMetric {
+		c.Ctx.metrics.wsMessages.WithLabelValues(c.path, direction).Inc()
+	}
+}
+
+// setReadDeadline set the first read deadline and extend it by pong, called before reading starts,
+// the pong handler runs in the reading goroutine
+func (c *WSConn) setReadDeadline() {
+	pongWait := c.conf.getPongWait()
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+}
+
+// keepalive ping the client until the connection is closed, only write methods are called
+func (c *WSConn) keepalive() {
+	ticker := time.NewTicker(c.conf.getPingInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.conf.getWriteTimeout())); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// WS register websocket handler on the default server
+func (e *App) WS(relativePath string, handler WSHandler, handlers ...HandlerFunc) {
+	e.defaultServer.WS(relativePath, handler, handlers...)
+}
+
+// WS register websocket handler, handlers are executed before upgrading
+// eg: g.WS("/chat", Chat, AuthFunc())
+func (g *RouterGroup) WS(relativePath string, handler WSHandler, handlers ...HandlerFunc) {
+	g.GET(relativePath, append(handlers, g.app.wsHandlerFunc(handler))...)
+}
+
+func (e *App) wsHandlerFunc(handler WSHandler) HandlerFunc {
+	return func(c *Context) {
+		conf := c.config.HTTPServer.WebSocket
+		upgrader := websocket.Upgrader{}
+		if conf.AllowAllOrigins {
+			upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+		}
+		header := http.Header{}
+		header.Set(getTraceIDConfig().getOutgoingHeader(), c.GetTraceID())
+		ws, err := upgrader.Upgrade(c.Gtx.Writer, c.Gtx.Request, header)
+		if err != nil {
+			// upgrader already replied the http error
+			c.Warnf("websocket upgrade failed, %v", err)
+			return
+		}
+		ws.SetReadLimit(conf.getMaxMessageSize())
+		conn := &WSConn{
+			Ctx:  c,
+			conn: ws,
+			path: c.Gtx.FullPath(),
+			conf: conf,
+			done: make(chan struct{}),
+		}
+		if !e.lifecycle.addWSConn(conn) {
+			conn.Close(websocket.CloseGoingAway, "server shutdown")
+			return
+		}
+		defer e.lifecycle.removeWSConn(conn)
+
+		startTime := time.Now()
+		if c.config.EnableMetric {
+			c.metrics.wsConnections.WithLabelValues(conn.path).Inc()
+			defer c.metrics.wsConnections.WithLabelValues(conn.path).Dec()
+		}
+		conn.setReadDeadline()
+		go conn.keepalive()
+		defer func() {
+			conn.Close(websocket.CloseNormalClosure, "")
+			if c.config.HTTPServer.DisableReqLog {
+				return
+			}
+			accessEntry(c.Entry).WithField(TraceLogKey, logBody{
+				TraceType:  TraceLogWebSocket,
+				TraceID:    c.GetTraceID(),
+				StatusCode: http.StatusSwitchingProtocols,
+				Duration:   time.Since(startTime).Milliseconds(),
+				Msg:        fmt.Sprintf("messages in %d, out %d", atomic.LoadInt64(&conn.in), atomic.LoadInt64(&conn.out)),
+				Path:       conn.path,
+			}).Info("")
+		}()
+		handler(conn)
+	}
+}
+
+// addWSConn track open connection, return false when the app is shutting down
+func (l *lifecycle) addWSConn(conn *WSConn) bool {
+	l.Lock()
+	defer l.Unlock()
+	if l.stopping {
+		return false
+	}
+	if l.wsConns == nil {
+		l.wsConns = map[*WSConn]struct{}{}
+	}
+	l.wsConns[conn] = struct{}{}
+	l.wsWG.Add(1)
+	return true
+}
+
+func (l *lifecycle) removeWSConn(conn *WSConn) {
+	l.Lock()
+	delete(l.wsConns, conn)
+	l.Unlock()
+	l.wsWG.Done()
+}
+
+// closeWSConns send going away close frame to open connections, wait handlers return
+func (l *lifecycle) closeWSConns(ctx context.Context) {
+	l.Lock()
+	l.stopping = true
+	conns := make([]*WSConn, 0, len(l.wsConns))
+	for conn := range l.wsConns {
+		conns = append(conns, conn)
+	}
+	l.Unlock()
+	for _, conn := range conns {
+		conn.Close(websocket.CloseGoingAway, "server shutdown")
+	}
+	done := make(chan struct{})
+	go func() {
+		l.wsWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
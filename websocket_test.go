@@ -0,0 +1,162 @@
+package frame
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newKeepaliveServer websocket server pinging the client and writing ticks until the read fails,
+// the read error is sent to the returned channel
+func newKeepaliveServer(t *testing.T, conf WebSocketConfig) (*httptest.Server, <-chan error) {
+	readErr := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		conn := &WSConn{Ctx: &Context{config: &Config{}}, conn: ws, path: "/ws", conf: conf, done: make(chan struct{})}
+		defer conn.Close(websocket.CloseNormalClosure, "")
+		conn.setReadDeadline()
+		go conn.keepalive()
+		// writes of the handler run concurrently with the pings of keepalive
+		go func() {
+			ticker := time.NewTicker(100 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := conn.WriteMessage(websocket.TextMessage, []byte("tick")); err != nil {
+						return
+					}
+				case <-conn.Done():
+					return
+				}
+			}
+		}()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, readErr
+}
+
+func dialTestWS(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func TestWSKeepalive(t *testing.T) {
+	conf := WebSocketConfig{PingIntervalSec: 1, PongWaitSec: 2}
+	tests := []struct {
+		name        string
+		read        bool // client reads, so pings are answered with pongs
+		wantTimeout bool
+	}{
+		{name: "pong extends read deadline", read: true},
+		{name: "no pong times out", read: false, wantTimeout: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, readErr := newKeepaliveServer(t, conf)
+			ws := dialTestWS(t, srv)
+			var pings, ticks int
+			ws.SetPingHandler(func(data string) error {
+				pings++
+				return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+			})
+			// outlive the pong wait of the server
+			deadline := time.Now().Add(conf.getPongWait() + 500*time.Millisecond)
+			if tt.read {
+				ws.SetReadDeadline(deadline)
+				for {
+					if _, _, err := ws.ReadMessage(); err != nil {
+						break
+					}
+					ticks++
+				}
+				ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+				if pings == 0 || ticks == 0 {
+					t.Errorf("client got %d pings and %d ticks, want both", pings, ticks)
+				}
+			} else {
+				time.Sleep(time.Until(deadline))
+			}
+
+			var err error
+			select {
+			case err = <-readErr:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("server read did not return")
+			}
+			var netErr net.Error
+			if timeout := errors.As(err, &netErr) && netErr.Timeout(); timeout != tt.wantTimeout {
+				t.Errorf("server read error = %v, want timeout %v", err, tt.wantTimeout)
+			}
+			if !tt.wantTimeout && !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Errorf("server read error = %v, want normal closure", err)
+			}
+		})
+	}
+}
+
+func TestWSConnClose(t *testing.T) {
+	srv, readErr := newKeepaliveServer(t, WebSocketConfig{})
+	ws := dialTestWS(t, srv)
+	// the close frame is already sent, do not echo the one of the server
+	ws.SetCloseHandler(func(int, string) error { return nil })
+	ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "bye"), time.Now().Add(time.Second))
+	select {
+	case err := <-readErr:
+		if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+			t.Errorf("server read error = %v, want going away", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server read did not return")
+	}
+	// the server echoes the close frame, the later one of Close is not sent
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				t.Errorf("client read error = %v, want going away", err)
+			}
+			break
+		}
+	}
+}
+
+func TestWebSocketConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    WebSocketConfig
+		wantErr bool
+	}{
+		{name: "default", conf: WebSocketConfig{}},
+		{name: "pong wait longer", conf: WebSocketConfig{PingIntervalSec: 10, PongWaitSec: 20}},
+		{name: "pong wait equal", conf: WebSocketConfig{PingIntervalSec: 10, PongWaitSec: 10}, wantErr: true},
+		{name: "default pong wait shorter", conf: WebSocketConfig{PingIntervalSec: 90}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := tt.conf.Validate(); (len(errs) > 0) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}